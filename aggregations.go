@@ -0,0 +1,470 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ValueCount holds a distinct field value and the number of documents having it.
+type ValueCount struct {
+	Value interface{} `bson:"_id"`
+	Count int64       `bson:"count"`
+}
+
+// TimeBucket holds the start of a time bucket and the number of documents falling into it.
+type TimeBucket struct {
+	Start time.Time `bson:"_id"`
+	Count int64     `bson:"count"`
+}
+
+// PeriodCount holds a period, e.g. "2024-03-10" for a day, and the number of documents falling into it.
+type PeriodCount struct {
+	Period string `bson:"_id"`
+	Count  int64  `bson:"count"`
+}
+
+// periodFormats maps the CountByPeriod granularities to the $dateToString formats.
+var periodFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"month": "%Y-%m",
+	"year":  "%Y",
+}
+
+// DistinctWithCounts returns the distinct values of the field among the documents matching the filters,
+// along with the number of documents having each value, ordered from the most to the least common.
+// Both scalar and array fields are supported: each element of an array is counted as a separate value.
+// Documents missing the field are ignored.
+// The function returns a slice of ValueCount and an error, if any.
+func (r *mongoRepository[T]) DistinctWithCounts(ctx context.Context, field string, filters ...FilterFunc) (_ []ValueCount, err error) {
+	ctx, op := r.startOperation(ctx, "DistinctWithCounts")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	// $unwind treats a non-array value as a single-element array, so scalar fields work as well
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$unwind", Value: "$" + field}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$" + field},
+			{Key: "count", Value: bson.M{"$sum": 1}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline, queryOptionsFromContext(ctx).aggregateOptions(options.Aggregate()))
+	if err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []ValueCount
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// Facet counts the documents matching the filters per distinct value of the groupBy field in one round trip,
+// e.g. the number of products per category for listing filters.
+// The map is keyed by the string form of the group value: strings are used as is, ObjectIDs as hex strings,
+// other values are formatted with fmt. Documents missing the field are counted under the empty key.
+// The function returns a map of the group values to the document counts and an error, if any.
+func (r *mongoRepository[T]) Facet(ctx context.Context, groupBy string, filters ...FilterFunc) (_ map[string]int64, err error) {
+	ctx, op := r.startOperation(ctx, "Facet")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$" + groupBy},
+			{Key: "count", Value: bson.M{"$sum": 1}},
+		}}},
+	}
+
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Aggregate(ctx, pipeline)
+	})
+	if err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []ValueCount
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+
+	results := make(map[string]int64, len(groups))
+	for _, g := range groups {
+		results[groupKey(g.Value)] += g.Count
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// Sum sums the numeric sumField of the documents matching the filters per distinct value of the groupBy field,
+// e.g. the order totals per customer. The map is keyed the same way as in Facet.
+// Documents where the sumField is missing, null or not a number add nothing to the sum,
+// so a group without numeric values has a zero sum.
+// Distinct group values with the same string form, e.g. 1 and "1", result in an error with the ErrGroupKeyCollision error code.
+// The function returns a map of the group values to the sums and an error, if any.
+func (r *mongoRepository[T]) Sum(ctx context.Context, groupBy, sumField string, filters ...FilterFunc) (_ map[string]float64, err error) {
+	ctx, op := r.startOperation(ctx, "Sum")
+	defer func() { op.end(err) }()
+
+	results, err := r.groupAccumulate(ctx, "$sum", groupBy, sumField, filters)
+	if err != nil {
+		return nil, err
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// Avg averages the numeric avgField of the documents matching the filters per distinct value of the groupBy field,
+// e.g. the average order total per customer. The map is keyed the same way as in Facet.
+// Documents where the avgField is missing, null or not a number are skipped, so they don't lower the average,
+// and groups without numeric values are left out of the map, since their average is undefined.
+// Distinct group values with the same string form, e.g. 1 and "1", result in an error with the ErrGroupKeyCollision error code.
+// The function returns a map of the group values to the averages and an error, if any.
+func (r *mongoRepository[T]) Avg(ctx context.Context, groupBy, avgField string, filters ...FilterFunc) (_ map[string]float64, err error) {
+	ctx, op := r.startOperation(ctx, "Avg")
+	defer func() { op.end(err) }()
+
+	results, err := r.groupAccumulate(ctx, "$avg", groupBy, avgField, filters)
+	if err != nil {
+		return nil, err
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// groupAccumulate applies the accumulator, e.g. $sum or $avg, to the field of the documents matching the filters
+// per distinct value of the groupBy field. Groups where the accumulator returns null are left out.
+// If distinct group values have the same string form, e.g. 1 and "1", it returns an error
+// with the ErrGroupKeyCollision error code.
+func (r *mongoRepository[T]) groupAccumulate(ctx context.Context, accumulator, groupBy, field string, filters []FilterFunc) (map[string]float64, error) {
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$" + groupBy},
+			{Key: "value", Value: bson.M{accumulator: "$" + field}},
+		}}},
+	}
+
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Aggregate(ctx, pipeline, queryOptionsFromContext(ctx).aggregateOptions(options.Aggregate()))
+	})
+	if err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Key   interface{} `bson:"_id"`
+		Value *float64    `bson:"value"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+
+	results := make(map[string]float64, len(groups))
+	for _, g := range groups {
+		if g.Value == nil {
+			continue
+		}
+		// Neither averages nor sums of distinct BSON types can be merged, e.g. the groups 1 and "1"
+		key := groupKey(g.Key)
+		if _, ok := results[key]; ok {
+			return nil, errors.Join(ErrFailedToAggregate, fmt.Errorf("%w: %q", ErrGroupKeyCollision, key))
+		}
+		results[key] = *g.Value
+	}
+	return results, nil
+}
+
+// groupKey returns the string form of a group value used as a map key.
+func groupKey(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case primitive.ObjectID:
+		return v.Hex()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// CountByTimeBucket counts the documents matching the filters per time bucket of the date field,
+// e.g. the number of events per day for a dashboard, ordered from the oldest to the newest bucket.
+// The unit is one of the $dateTrunc units: "year", "quarter", "month", "week", "day", "hour", "minute" or "second".
+// The bucket boundaries are computed in the given timezone, so that "per day" matches the user's calendar;
+// the timezone is an Olson timezone identifier, e.g. "Europe/Berlin", or a UTC offset, e.g. "+03:00",
+// and an empty timezone means UTC. The start of each bucket is returned as an instant in time,
+// e.g. the local midnight of the day. It requires MongoDB 5.0 or later.
+// Documents missing the field are ignored.
+// The function returns a slice of TimeBucket and an error, if any.
+func (r *mongoRepository[T]) CountByTimeBucket(ctx context.Context, field, unit, timezone string, filters ...FilterFunc) (_ []TimeBucket, err error) {
+	ctx, op := r.startOperation(ctx, "CountByTimeBucket")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$match", Value: bson.M{field: bson.M{"$type": "date"}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.M{"$dateTrunc": bson.M{"date": "$" + field, "unit": unit, "timezone": timezone}}},
+			{Key: "count", Value: bson.M{"$sum": 1}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Aggregate(ctx, pipeline)
+	})
+	if err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []TimeBucket
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// JoinStream streams the documents matching the filters, joined with the documents of the from collection
+// where foreignField equals localField, through the callback one by one, without buffering the whole result set,
+// e.g. for large exports. The joined documents are put into the as field as an array.
+// The documents are passed as bson.M, since their shape differs from T after the join.
+// If the callback returns an error, the iteration stops and the error is returned.
+// The function returns an error if the aggregation fails.
+func (r *mongoRepository[T]) JoinStream(ctx context.Context, from, localField, foreignField, as string, fn func(bson.M) error, filters ...FilterFunc) (err error) {
+	ctx, op := r.startStreamingOperation(ctx, "JoinStream")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: from},
+			{Key: "localField", Value: localField},
+			{Key: "foreignField", Value: foreignField},
+			{Key: "as", Value: as},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return errors.Join(ErrFailedToAggregate, err)
+	}
+	defer cursor.Close(ctx)
+
+	var count int64
+	defer func() { op.setDocumentCount(count) }()
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return errors.Join(ErrFailedToAggregate, err)
+		}
+		count++
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return errors.Join(ErrFailedToAggregate, err)
+	}
+	return nil
+}
+
+// FindPageFaceted returns a page of the documents matching the filters along with the total number
+// of matching documents in a single round trip, using $facet with the page data and the count sub-pipelines,
+// instead of two separate queries. If limit is zero, the default limit is applied, like in FindManyByFilter,
+// and NoLimit returns all matching documents.
+// The sort order carried by the context with WithSort is applied before paging.
+// Note that the whole page must fit into a single 16MB result document.
+// The function returns the page documents, the total number of matching documents and an error, if any.
+func (r *mongoRepository[T]) FindPageFaceted(ctx context.Context, skip, limit int64, filters ...FilterFunc) (items []T, total int64, err error) {
+	ctx, op := r.startOperation(ctx, "FindPageFaceted")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	limit, err = r.opts.limit(limit)
+	if err != nil {
+		return nil, 0, errors.Join(ErrFailedToAggregate, err)
+	}
+
+	data := bson.A{}
+	qopts := queryOptionsFromContext(ctx)
+	if qopts.sort != nil {
+		data = append(data, bson.D{{Key: "$sort", Value: qopts.sort}})
+	}
+	data = append(data, bson.D{{Key: "$skip", Value: skip}})
+	if limit > 0 {
+		data = append(data, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "data", Value: data},
+			{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+		}}},
+	}
+
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Aggregate(ctx, pipeline, qopts.aggregateOptions(options.Aggregate()))
+	})
+	if err != nil {
+		return nil, 0, errors.Join(ErrFailedToAggregate, err)
+	}
+	defer cursor.Close(ctx)
+
+	var page struct {
+		Data  []T `bson:"data"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&page); err != nil {
+			return nil, 0, errors.Join(ErrFailedToAggregate, err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, 0, errors.Join(ErrFailedToAggregate, err)
+	}
+	if len(page.Total) > 0 {
+		total = page.Total[0].Count
+	}
+
+	op.setDocumentCount(int64(len(page.Data)))
+	return page.Data, total, nil
+}
+
+// CountByPeriod counts the documents matching the filters per period of the date field, e.g. for admin charts,
+// ordered from the oldest to the newest period. The granularity is one of "day", "month" or "year",
+// and the periods are formatted as "2006-01-02", "2006-01" and "2006" respectively.
+// The periods are computed in UTC; use CountByTimeBucket to group in the user's timezone.
+// Documents missing the field are ignored.
+// The function returns a slice of PeriodCount and an error, if any.
+func (r *mongoRepository[T]) CountByPeriod(ctx context.Context, dateField string, granularity string, filters ...FilterFunc) (_ []PeriodCount, err error) {
+	ctx, op := r.startOperation(ctx, "CountByPeriod")
+	defer func() { op.end(err) }()
+
+	format, ok := periodFormats[granularity]
+	if !ok {
+		return nil, errors.Join(ErrFailedToAggregate, ErrInvalidGranularity, fmt.Errorf("unsupported granularity %q", granularity))
+	}
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$match", Value: bson.M{dateField: bson.M{"$type": "date"}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.M{"$dateToString": bson.M{"format": format, "date": "$" + dateField}}},
+			{Key: "count", Value: bson.M{"$sum": 1}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Aggregate(ctx, pipeline)
+	})
+	if err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []PeriodCount
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// Aggregate runs the aggregation pipeline on the collection and returns the cursor over its results,
+// e.g. for pipelines whose output doesn't match T. The caller must close the cursor.
+// The collation and the hint carried by the context with WithQueryOptions are applied to the aggregation.
+// The cursor is used by the caller after Aggregate returns, so the default timeout set by WithDefaultTimeout
+// isn't applied to it; pass a context with a deadline to limit it.
+// It returns an error with the ErrFailedToAggregate error code if the aggregation fails.
+func (r *mongoRepository[T]) Aggregate(ctx context.Context, pipeline mongo.Pipeline) (_ *mongo.Cursor, err error) {
+	ctx, op := r.startStreamingOperation(ctx, "Aggregate")
+	defer func() { op.end(err) }()
+
+	aggregateOptions := queryOptionsFromContext(ctx).aggregateOptions(options.Aggregate())
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Aggregate(ctx, pipeline, aggregateOptions)
+	})
+	if err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	return cursor, nil
+}
+
+// AggregateTyped runs the aggregation pipeline on the collection of the repository and decodes the results
+// into a slice of R, e.g. a struct matching the output of a $group stage, for reporting queries.
+// It is a function rather than a method, since Go methods can't have their own type parameters.
+// It works with any Repository, running the pipeline with its Aggregate method.
+// No results are returned as an empty slice, not an error.
+// It returns an error with the ErrFailedToAggregate error code if the aggregation fails.
+func AggregateTyped[T, R any](ctx context.Context, repo Repository[T], pipeline mongo.Pipeline) ([]R, error) {
+	cursor, err := repo.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []R{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, errors.Join(ErrFailedToAggregate, err)
+	}
+	return results, nil
+}
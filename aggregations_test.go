@@ -0,0 +1,388 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestAggregations(t *testing.T) {
+	type Post struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Status string             `bson:"status"`
+		Tags   []string           `bson:"tags,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Post](db, "posts")
+
+	posts := []Post{
+		{Status: "published", Tags: []string{"go", "mongodb", "backend"}},
+		{Status: "published", Tags: []string{"go", "mongodb"}},
+		{Status: "draft", Tags: []string{"go"}},
+		{Status: "published"},
+	}
+	for _, post := range posts {
+		_, err := repo.Create(context.Background(), post)
+		require.NoError(t, err)
+	}
+
+	t.Run("DistinctWithCounts", func(t *testing.T) {
+		counts, err := repo.DistinctWithCounts(context.Background(), "tags")
+		require.NoError(t, err)
+		require.Len(t, counts, 3)
+		assert.Equal(t, "go", counts[0].Value)
+		assert.Equal(t, int64(3), counts[0].Count)
+		assert.Equal(t, "mongodb", counts[1].Value)
+		assert.Equal(t, int64(2), counts[1].Count)
+		assert.Equal(t, "backend", counts[2].Value)
+		assert.Equal(t, int64(1), counts[2].Count)
+
+		// Scalar field with filter
+		counts, err = repo.DistinctWithCounts(context.Background(), "status", mongorepository.Eq("tags", "go"))
+		require.NoError(t, err)
+		require.Len(t, counts, 2)
+		assert.Equal(t, "published", counts[0].Value)
+		assert.Equal(t, int64(2), counts[0].Count)
+	})
+
+	t.Run("Facet", func(t *testing.T) {
+		counts, err := repo.Facet(context.Background(), "status")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"published": 3, "draft": 1}, counts)
+
+		// Filters are applied before grouping
+		counts, err = repo.Facet(context.Background(), "status", mongorepository.Eq("tags", "mongodb"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"published": 2}, counts)
+	})
+}
+
+func TestCountByTimeBucket(t *testing.T) {
+	type Event struct {
+		ID         primitive.ObjectID `bson:"_id,omitempty"`
+		OccurredAt time.Time          `bson:"occurred_at"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Event](db, "events")
+
+	// Two events around midnight UTC
+	for _, occurredAt := range []time.Time{
+		time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC),
+		time.Date(2024, 3, 11, 0, 30, 0, 0, time.UTC),
+	} {
+		_, err := repo.Create(context.Background(), Event{OccurredAt: occurredAt})
+		require.NoError(t, err)
+	}
+
+	t.Run("UTC", func(t *testing.T) {
+		buckets, err := repo.CountByTimeBucket(context.Background(), "occurred_at", "day", "")
+		require.NoError(t, err)
+		require.Len(t, buckets, 2)
+		assert.True(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC).Equal(buckets[0].Start))
+		assert.Equal(t, int64(1), buckets[0].Count)
+		assert.True(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC).Equal(buckets[1].Start))
+		assert.Equal(t, int64(1), buckets[1].Count)
+	})
+
+	t.Run("Timezone", func(t *testing.T) {
+		// Both events happened on March 11 in Berlin (UTC+1)
+		buckets, err := repo.CountByTimeBucket(context.Background(), "occurred_at", "day", "Europe/Berlin")
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.True(t, time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC).Equal(buckets[0].Start))
+		assert.Equal(t, int64(2), buckets[0].Count)
+
+		// And on March 10 in New York (UTC-4)
+		buckets, err = repo.CountByTimeBucket(context.Background(), "occurred_at", "day", "America/New_York")
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.True(t, time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC).Equal(buckets[0].Start))
+		assert.Equal(t, int64(2), buckets[0].Count)
+	})
+}
+
+func TestJoinStream(t *testing.T) {
+	type Author struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+	type Book struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Title    string             `bson:"title"`
+		AuthorID primitive.ObjectID `bson:"author_id"`
+	}
+
+	db := setupMongoDB(t)
+	authors := mongorepository.NewMongoRepository[Author](db, "authors")
+	books := mongorepository.NewMongoRepository[Book](db, "books")
+
+	author := Author{ID: primitive.NewObjectID(), Name: "Jane Austen"}
+	_, err := authors.Create(context.Background(), author)
+	require.NoError(t, err)
+	for _, title := range []string{"Emma", "Persuasion"} {
+		_, err := books.Create(context.Background(), Book{Title: title, AuthorID: author.ID})
+		require.NoError(t, err)
+	}
+	_, err = books.Create(context.Background(), Book{Title: "Orphan", AuthorID: primitive.NewObjectID()})
+	require.NoError(t, err)
+
+	joined := map[string]int{}
+	err = books.JoinStream(context.Background(), "authors", "author_id", "_id", "author", func(doc bson.M) error {
+		title, _ := doc["title"].(string)
+		author, ok := doc["author"].(bson.A)
+		require.True(t, ok)
+		joined[title] = len(author)
+		if len(author) > 0 {
+			assert.Equal(t, "Jane Austen", author[0].(bson.M)["name"])
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"Emma": 1, "Persuasion": 1, "Orphan": 0}, joined)
+
+	// Filters are applied before the join
+	calls := 0
+	err = books.JoinStream(context.Background(), "authors", "author_id", "_id", "author", func(bson.M) error {
+		calls++
+		return nil
+	}, mongorepository.Eq("title", "Emma"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestFindPageFaceted(t *testing.T) {
+	type Product struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Index    int                `bson:"index"`
+		Category string             `bson:"category"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Product](db, "products")
+
+	for i := 0; i < 25; i++ {
+		category := "books"
+		if i%5 == 0 {
+			category = "music"
+		}
+		_, err := repo.Create(context.Background(), Product{Index: i, Category: category})
+		require.NoError(t, err)
+	}
+
+	ctx := mongorepository.WithQueryOptions(context.Background(), mongorepository.WithSort(bson.D{{Key: "index", Value: 1}}))
+
+	t.Run("FirstPage", func(t *testing.T) {
+		items, total, err := repo.FindPageFaceted(ctx, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(25), total)
+		require.Len(t, items, 10)
+		assert.Equal(t, 0, items[0].Index)
+		assert.Equal(t, 9, items[9].Index)
+	})
+
+	t.Run("LastPage", func(t *testing.T) {
+		items, total, err := repo.FindPageFaceted(ctx, 20, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(25), total)
+		require.Len(t, items, 5)
+		assert.Equal(t, 20, items[0].Index)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		items, total, err := repo.FindPageFaceted(ctx, 0, 2, mongorepository.Eq("category", "music"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+		require.Len(t, items, 2)
+		assert.Equal(t, 0, items[0].Index)
+		assert.Equal(t, 5, items[1].Index)
+	})
+
+	t.Run("NoMatches", func(t *testing.T) {
+		items, total, err := repo.FindPageFaceted(ctx, 0, 10, mongorepository.Eq("category", "games"))
+		require.NoError(t, err)
+		assert.Zero(t, total)
+		assert.Empty(t, items)
+	})
+}
+
+func TestCountByPeriod(t *testing.T) {
+	type Order struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		CreatedAt time.Time          `bson:"created_at"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Order](db, "orders")
+
+	for _, createdAt := range []time.Time{
+		time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC),
+	} {
+		_, err := repo.Create(context.Background(), Order{CreatedAt: createdAt})
+		require.NoError(t, err)
+	}
+
+	t.Run("Day", func(t *testing.T) {
+		counts, err := repo.CountByPeriod(context.Background(), "created_at", "day")
+		require.NoError(t, err)
+		assert.Equal(t, []mongorepository.PeriodCount{
+			{Period: "2023-12-31", Count: 1},
+			{Period: "2024-01-01", Count: 2},
+			{Period: "2024-01-03", Count: 1},
+			{Period: "2024-02-01", Count: 1},
+		}, counts)
+	})
+
+	t.Run("Month", func(t *testing.T) {
+		counts, err := repo.CountByPeriod(context.Background(), "created_at", "month")
+		require.NoError(t, err)
+		assert.Equal(t, []mongorepository.PeriodCount{
+			{Period: "2023-12", Count: 1},
+			{Period: "2024-01", Count: 3},
+			{Period: "2024-02", Count: 1},
+		}, counts)
+	})
+
+	t.Run("Year", func(t *testing.T) {
+		counts, err := repo.CountByPeriod(context.Background(), "created_at", "year",
+			mongorepository.Gte("created_at", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+		require.NoError(t, err)
+		assert.Equal(t, []mongorepository.PeriodCount{{Period: "2024", Count: 4}}, counts)
+	})
+
+	t.Run("InvalidGranularity", func(t *testing.T) {
+		_, err := repo.CountByPeriod(context.Background(), "created_at", "week")
+		require.ErrorIs(t, err, mongorepository.ErrInvalidGranularity)
+	})
+}
+
+func TestAggregateTyped(t *testing.T) {
+	type Order struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Customer string             `bson:"customer"`
+		Total    int64              `bson:"total"`
+	}
+	type CustomerTotal struct {
+		Customer string `bson:"_id"`
+		Orders   int64  `bson:"orders"`
+		Total    int64  `bson:"total"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Order](db, "orders")
+	for _, order := range []Order{
+		{Customer: "John", Total: 100},
+		{Customer: "Jane", Total: 50},
+		{Customer: "John", Total: 25},
+	} {
+		_, err := repo.Create(context.Background(), order)
+		require.NoError(t, err)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$customer"},
+			{Key: "orders", Value: bson.M{"$sum": 1}},
+			{Key: "total", Value: bson.M{"$sum": "$total"}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "total", Value: -1}}}},
+	}
+	totals, err := mongorepository.AggregateTyped[Order, CustomerTotal](context.Background(), repo, pipeline)
+	require.NoError(t, err)
+	assert.Equal(t, []CustomerTotal{
+		{Customer: "John", Orders: 2, Total: 125},
+		{Customer: "Jane", Orders: 1, Total: 50},
+	}, totals)
+
+	t.Run("Empty", func(t *testing.T) {
+		totals, err := mongorepository.AggregateTyped[Order, CustomerTotal](context.Background(), repo, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"customer": "Alex"}}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, totals)
+	})
+
+	t.Run("InvalidPipeline", func(t *testing.T) {
+		_, err := mongorepository.AggregateTyped[Order, CustomerTotal](context.Background(), repo, mongo.Pipeline{
+			{{Key: "$unknownStage", Value: bson.M{}}},
+		})
+		require.ErrorIs(t, err, mongorepository.ErrFailedToAggregate)
+	})
+
+	t.Run("Repository", func(t *testing.T) {
+		// Any Repository works, e.g. the one returned by ForDatabase
+		var r mongorepository.Repository[Order] = repo.ForDatabase(db)
+		totals, err := mongorepository.AggregateTyped[Order, CustomerTotal](context.Background(), r, pipeline)
+		require.NoError(t, err)
+		assert.Len(t, totals, 2)
+
+		r = mongorepository.NewInMemoryRepository[Order]()
+		_, err = mongorepository.AggregateTyped[Order, CustomerTotal](context.Background(), r, pipeline)
+		require.ErrorIs(t, err, mongorepository.ErrNotSupported)
+	})
+}
+
+func TestSumAvg(t *testing.T) {
+	type Order struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Customer string             `bson:"customer"`
+		Total    *float64           `bson:"total"`
+	}
+	total := func(v float64) *float64 { return &v }
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Order](db, "orders")
+	for _, order := range []Order{
+		{Customer: "John", Total: total(100)},
+		{Customer: "John", Total: total(50)},
+		{Customer: "John"}, // null total
+		{Customer: "Jane", Total: total(30.5)},
+		{Customer: "Alex"},
+	} {
+		_, err := repo.Create(context.Background(), order)
+		require.NoError(t, err)
+	}
+
+	t.Run("Sum", func(t *testing.T) {
+		sums, err := repo.Sum(context.Background(), "customer", "total")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{"John": 150, "Jane": 30.5, "Alex": 0}, sums)
+
+		sums, err = repo.Sum(context.Background(), "customer", "total", mongorepository.Eq("customer", "John"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{"John": 150}, sums)
+	})
+
+	t.Run("Avg", func(t *testing.T) {
+		avgs, err := repo.Avg(context.Background(), "customer", "total")
+		require.NoError(t, err)
+		// Null totals are skipped
+		assert.Equal(t, map[string]float64{"John": 75, "Jane": 30.5}, avgs)
+	})
+
+	t.Run("KeyCollision", func(t *testing.T) {
+		// The groups 1 and "1" can't be told apart in the map
+		_, err := db.Collection("orders").InsertMany(context.Background(), []interface{}{
+			bson.M{"customer": 1, "total": 10},
+			bson.M{"customer": "1", "total": 20},
+		})
+		require.NoError(t, err)
+
+		_, err = repo.Avg(context.Background(), "customer", "total")
+		require.ErrorIs(t, err, mongorepository.ErrGroupKeyCollision)
+		_, err = repo.Sum(context.Background(), "customer", "total")
+		require.ErrorIs(t, err, mongorepository.ErrGroupKeyCollision)
+	})
+}
@@ -0,0 +1,202 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// writeOpKind is the kind of a bulk write operation.
+type writeOpKind int
+
+const (
+	insertOpKind writeOpKind = iota + 1
+	updateOpKind
+	deleteOpKind
+)
+
+// WriteOp is a single operation of a bulk write, created by InsertOp, UpdateOp or DeleteOp.
+type WriteOp struct {
+	kind   writeOpKind
+	model  interface{}
+	filter FilterFunc
+	update map[string]interface{}
+}
+
+// InsertOp creates a bulk write operation inserting the model.
+// The model must be of the repository document type, otherwise BulkWrite fails with ErrInvalidWriteOp.
+func InsertOp(model interface{}) WriteOp {
+	return WriteOp{kind: insertOpKind, model: model}
+}
+
+// UpdateOp creates a bulk write operation setting the fields of the update map
+// on all documents matching the filter, the same way as UpdateMany. A nil filter matches all documents.
+func UpdateOp(filter FilterFunc, update map[string]interface{}) WriteOp {
+	return WriteOp{kind: updateOpKind, filter: filter, update: update}
+}
+
+// DeleteOp creates a bulk write operation deleting all documents matching the filter,
+// the same way as DeleteMany. A nil filter matches all documents.
+func DeleteOp(filter FilterFunc) WriteOp {
+	return WriteOp{kind: deleteOpKind, filter: filter}
+}
+
+// BulkWrite executes mixed insert, update and delete operations in one round trip.
+// If ordered is true, the operations are executed sequentially and the execution stops at the first failure;
+// otherwise, they may be executed in any order and the remaining operations are attempted after a failure.
+// If an operation fails, the returned BulkResult still holds the counts of the operations applied before the error.
+// Inserted models are validated like in Create, and a duplicate key results in the ErrDuplicate error code.
+// The function returns a BulkResult and an error, if any.
+func (r *mongoRepository[T]) BulkWrite(ctx context.Context, ops []WriteOp, ordered bool) (_ BulkResult, err error) {
+	ctx, op := r.startOperation(ctx, "BulkWrite")
+	defer func() { op.end(err) }()
+
+	if len(ops) == 0 {
+		return BulkResult{}, nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(ops))
+	for i, wop := range ops {
+		model, err := r.writeModel(wop)
+		if err != nil {
+			return BulkResult{}, errors.Join(ErrFailedToBulkWrite, fmt.Errorf("operation %d: %w", i, err))
+		}
+		models = append(models, model)
+	}
+
+	result, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(ordered))
+	bulkResult := newBulkResult(result)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return bulkResult, errors.Join(ErrFailedToBulkWrite, ErrDuplicate, err)
+		}
+		return bulkResult, errors.Join(ErrFailedToBulkWrite, err)
+	}
+	op.setDocumentCount(bulkResult.InsertedCount + bulkResult.ModifiedCount + bulkResult.DeletedCount + bulkResult.UpsertedCount)
+	return bulkResult, nil
+}
+
+// UpsertMany inserts or updates the models keyed by the value of keyField, e.g. an external id, in one round trip,
+// which is useful for sync jobs importing records from another system. For each model, the document
+// with the same keyField value gets the fields of the model set, the same way as Update, or the model
+// is inserted if there is none. The _id of the models is ignored, so the existing documents keep theirs.
+// The operations are ordered, so a key repeated in the models is inserted once and then updated.
+// Index keyField, ideally with a unique index, so the lookups are cheap and concurrent upserts of the same key
+// don't insert duplicates. Models are validated like in Create, and a model missing keyField
+// fails the whole call with the ErrInvalidWriteOp error code before anything is written.
+// It returns a BulkResult with the matched, modified and upserted counts and an error, if any.
+func (r *mongoRepository[T]) UpsertMany(ctx context.Context, keyField string, models []T) (_ BulkResult, err error) {
+	ctx, op := r.startOperation(ctx, "UpsertMany")
+	defer func() { op.end(err) }()
+
+	if len(models) == 0 {
+		return BulkResult{}, nil
+	}
+
+	writeModels := make([]mongo.WriteModel, 0, len(models))
+	for i := range models {
+		if err := validate(&models[i]); err != nil {
+			return BulkResult{}, errors.Join(ErrFailedToBulkWrite, fmt.Errorf("model %d: %w", i, err))
+		}
+		doc, err := toBsonD(models[i])
+		if err != nil {
+			return BulkResult{}, errors.Join(ErrFailedToBulkWrite, fmt.Errorf("model %d: %w", i, err))
+		}
+		key, ok := lookupField(doc, keyField)
+		if !ok {
+			return BulkResult{}, errors.Join(ErrFailedToBulkWrite, ErrInvalidWriteOp, fmt.Errorf("model %d: missing key field %q", i, keyField))
+		}
+		fields := make(bson.D, 0, len(doc))
+		for _, e := range doc {
+			if e.Key != "_id" {
+				fields = append(fields, e)
+			}
+		}
+		writeModels = append(writeModels, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: keyField, Value: key}}).
+			SetUpdate(bson.D{{Key: "$set", Value: fields}}).
+			SetUpsert(true))
+	}
+
+	result, err := r.collection.BulkWrite(ctx, writeModels, options.BulkWrite().SetOrdered(true))
+	bulkResult := newBulkResult(result)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return bulkResult, errors.Join(ErrFailedToBulkWrite, ErrDuplicate, err)
+		}
+		return bulkResult, errors.Join(ErrFailedToBulkWrite, err)
+	}
+	op.setDocumentCount(bulkResult.ModifiedCount + bulkResult.UpsertedCount)
+	return bulkResult, nil
+}
+
+// writeModel converts the bulk write operation into the driver's write model.
+func (r *mongoRepository[T]) writeModel(wop WriteOp) (mongo.WriteModel, error) {
+	switch wop.kind {
+	case insertOpKind:
+		model, ok := wop.model.(T)
+		if !ok {
+			return nil, errors.Join(ErrInvalidWriteOp, fmt.Errorf("unexpected model type %T", wop.model))
+		}
+		if err := validate(&model); err != nil {
+			return nil, err
+		}
+		return mongo.NewInsertOneModel().SetDocument(model), nil
+	case updateOpKind:
+		return mongo.NewUpdateManyModel().
+			SetFilter(writeOpFilter(wop.filter)).
+			SetUpdate(bson.M{"$set": wop.update}), nil
+	case deleteOpKind:
+		return mongo.NewDeleteManyModel().SetFilter(writeOpFilter(wop.filter)), nil
+	default:
+		return nil, ErrInvalidWriteOp
+	}
+}
+
+// writeOpFilter builds the filter document of a bulk write operation.
+func writeOpFilter(f FilterFunc) bson.D {
+	if f == nil {
+		return bson.D{}
+	}
+	return f(bson.D{})
+}
+
+// BulkResult is the result of a bulk write operation.
+// It keeps the public API independent from the driver's mongo.BulkWriteResult.
+type BulkResult struct {
+	InsertedCount int64            // Number of inserted documents
+	MatchedCount  int64            // Number of documents matched by update and replace operations
+	ModifiedCount int64            // Number of documents modified by update and replace operations
+	DeletedCount  int64            // Number of deleted documents
+	UpsertedCount int64            // Number of documents inserted by upserts
+	UpsertedIDs   map[int64]string // IDs of the upserted documents keyed by the index of the operation
+}
+
+// newBulkResult converts the driver's bulk write result into a BulkResult.
+// ObjectIDs are converted into hex strings, other ids into their default string form.
+func newBulkResult(result *mongo.BulkWriteResult) BulkResult {
+	if result == nil {
+		return BulkResult{}
+	}
+	upsertedIDs := make(map[int64]string, len(result.UpsertedIDs))
+	for idx, id := range result.UpsertedIDs {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			upsertedIDs[idx] = oid.Hex()
+		} else {
+			upsertedIDs[idx] = fmt.Sprint(id)
+		}
+	}
+	return BulkResult{
+		InsertedCount: result.InsertedCount,
+		MatchedCount:  result.MatchedCount,
+		ModifiedCount: result.ModifiedCount,
+		DeletedCount:  result.DeletedCount,
+		UpsertedCount: result.UpsertedCount,
+		UpsertedIDs:   upsertedIDs,
+	}
+}
@@ -0,0 +1,32 @@
+package mongorepository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestNewBulkResult(t *testing.T) {
+	oid := primitive.NewObjectID()
+	result := newBulkResult(&mongo.BulkWriteResult{
+		InsertedCount: 2,
+		MatchedCount:  3,
+		ModifiedCount: 1,
+		DeletedCount:  4,
+		UpsertedCount: 2,
+		UpsertedIDs:   map[int64]interface{}{1: oid, 5: "external-42"},
+	})
+
+	assert.Equal(t, BulkResult{
+		InsertedCount: 2,
+		MatchedCount:  3,
+		ModifiedCount: 1,
+		DeletedCount:  4,
+		UpsertedCount: 2,
+		UpsertedIDs:   map[int64]string{1: oid.Hex(), 5: "external-42"},
+	}, result)
+
+	assert.Equal(t, BulkResult{}, newBulkResult(nil))
+}
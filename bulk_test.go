@@ -0,0 +1,124 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBulkWrite(t *testing.T) {
+	type Task struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Title  string             `bson:"title"`
+		Status string             `bson:"status"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Task](db, "tasks")
+
+	for _, task := range []Task{
+		{Title: "write docs", Status: "todo"},
+		{Title: "fix bug", Status: "todo"},
+		{Title: "old task", Status: "done"},
+	} {
+		_, err := repo.Create(context.Background(), task)
+		require.NoError(t, err)
+	}
+
+	t.Run("MixedOperations", func(t *testing.T) {
+		result, err := repo.BulkWrite(context.Background(), []mongorepository.WriteOp{
+			mongorepository.InsertOp(Task{Title: "release", Status: "todo"}),
+			mongorepository.InsertOp(Task{Title: "review", Status: "todo"}),
+			mongorepository.UpdateOp(mongorepository.Eq("title", "fix bug"), map[string]interface{}{"status": "in_progress"}),
+			mongorepository.DeleteOp(mongorepository.Eq("status", "done")),
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), result.InsertedCount)
+		assert.Equal(t, int64(1), result.MatchedCount)
+		assert.Equal(t, int64(1), result.ModifiedCount)
+		assert.Equal(t, int64(1), result.DeletedCount)
+
+		count, err := repo.Count(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), count)
+
+		task, err := repo.FindOneByFilter(context.Background(), mongorepository.Eq("title", "fix bug"))
+		require.NoError(t, err)
+		assert.Equal(t, "in_progress", task.Status)
+	})
+
+	t.Run("InvalidModel", func(t *testing.T) {
+		_, err := repo.BulkWrite(context.Background(), []mongorepository.WriteOp{
+			mongorepository.InsertOp(struct{ Title string }{Title: "wrong type"}),
+		}, true)
+		require.ErrorIs(t, err, mongorepository.ErrInvalidWriteOp)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		result, err := repo.BulkWrite(context.Background(), nil, true)
+		require.NoError(t, err)
+		assert.Zero(t, result.InsertedCount)
+	})
+}
+
+func TestUpsertMany(t *testing.T) {
+	type Product struct {
+		ID         primitive.ObjectID `bson:"_id,omitempty"`
+		ExternalID string             `bson:"external_id"`
+		Name       string             `bson:"name"`
+		Price      float64            `bson:"price"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Product](db, "products")
+	require.NoError(t, repo.CreateIndex(context.Background(), "external_id", mongorepository.Unique(true)))
+
+	products := []Product{
+		{ExternalID: "p1", Name: "Keyboard", Price: 50},
+		{ExternalID: "p2", Name: "Mouse", Price: 20},
+		{ExternalID: "p3", Name: "Monitor", Price: 200},
+	}
+
+	t.Run("Insert", func(t *testing.T) {
+		result, err := repo.UpsertMany(context.Background(), "external_id", products)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), result.UpsertedCount)
+		assert.Zero(t, result.MatchedCount)
+		assert.Zero(t, result.ModifiedCount)
+		assert.Len(t, result.UpsertedIDs, 3)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		first, err := repo.FindOneByFilter(context.Background(), mongorepository.Eq("external_id", "p1"))
+		require.NoError(t, err)
+
+		products[1].Price = 25
+		result, err := repo.UpsertMany(context.Background(), "external_id", products)
+		require.NoError(t, err)
+		assert.Zero(t, result.UpsertedCount)
+		assert.Equal(t, int64(3), result.MatchedCount)
+		assert.Equal(t, int64(1), result.ModifiedCount)
+
+		count, err := repo.Count(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		// The existing documents keep their ids
+		updated, err := repo.FindOneByFilter(context.Background(), mongorepository.Eq("external_id", "p1"))
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, updated.ID)
+
+		mouse, err := repo.FindOneByFilter(context.Background(), mongorepository.Eq("external_id", "p2"))
+		require.NoError(t, err)
+		assert.Equal(t, 25.0, mouse.Price)
+	})
+
+	t.Run("MissingKeyField", func(t *testing.T) {
+		_, err := repo.UpsertMany(context.Background(), "sku", products)
+		require.ErrorIs(t, err, mongorepository.ErrInvalidWriteOp)
+	})
+}
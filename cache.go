@@ -0,0 +1,157 @@
+package mongorepository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// resultCache is an in-memory cache of query results with per-entry expiration.
+type resultCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry[T]
+}
+
+// cacheEntry is a cached query result.
+type cacheEntry[T any] struct {
+	results   []T
+	expiresAt time.Time
+}
+
+// newResultCache creates a new empty resultCache.
+func newResultCache[T any]() *resultCache[T] {
+	return &resultCache[T]{entries: make(map[string]cacheEntry[T])}
+}
+
+// get returns a copy of the cached results for the key, if they haven't expired yet.
+func (c *resultCache[T]) get(key string) ([]T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return append([]T(nil), entry.results...), true
+}
+
+// set stores a copy of the results for the key and purges the expired entries.
+func (c *resultCache[T]) set(key string, results []T, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry[T]{
+		results:   append([]T(nil), results...),
+		expiresAt: now.Add(ttl),
+	}
+}
+
+// CachedFindMany works as FindManyByFilter, but caches the results in memory for the given TTL.
+// Repeated identical queries, i.e. with the same filters, skip, limit and query options, return the cached results
+// without hitting the database until the TTL expires. The cache is invalidated by the TTL only,
+// so the results can be stale up to the TTL after the documents are changed.
+func (r *mongoRepository[T]) CachedFindMany(ctx context.Context, ttl time.Duration, skip, limit int64, filters ...FilterFunc) (_ []T, err error) {
+	ctx, op := r.startOperation(ctx, "CachedFindMany")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	key, err := cacheKey(filter, skip, limit, queryOptionsFromContext(ctx))
+	if err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+
+	if results, ok := r.cache.get(key); ok {
+		op.setDocumentCount(int64(len(results)))
+		return results, nil
+	}
+
+	results, err := r.FindManyByFilter(ctx, skip, limit, filters...)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(key, results, ttl)
+
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// cacheKey returns a stable hash of the query, including the query options carried by the context,
+// since they change the returned documents or their order.
+func cacheKey(filter bson.D, skip, limit int64, qopts queryOptions) (string, error) {
+	var collation interface{}
+	if qopts.collation != nil {
+		collation = qopts.collation.ToDocument()
+	}
+	data, err := bson.Marshal(bson.D{
+		{Key: "filter", Value: normalizeDocument(filter)},
+		{Key: "skip", Value: skip},
+		{Key: "limit", Value: limit},
+		{Key: "sort", Value: qopts.sort},
+		{Key: "collation", Value: collation},
+		{Key: "hint", Value: qopts.hint},
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalizeDocument converts the maps within the value into documents sorted by key,
+// since the iteration order of maps, and so their encoding, is random.
+func normalizeDocument(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.M:
+		return normalizeMap(v)
+	case map[string]interface{}:
+		return normalizeMap(v)
+	case bson.D:
+		doc := make(bson.D, len(v))
+		for i, e := range v {
+			doc[i] = bson.E{Key: e.Key, Value: normalizeDocument(e.Value)}
+		}
+		return doc
+	case []bson.E:
+		return normalizeDocument(bson.D(v))
+	case bson.A:
+		arr := make(bson.A, len(v))
+		for i, e := range v {
+			arr[i] = normalizeDocument(e)
+		}
+		return arr
+	}
+	return value
+}
+
+// normalizeMap converts the map into a document sorted by key.
+func normalizeMap(m map[string]interface{}) bson.D {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	doc := make(bson.D, len(keys))
+	for i, k := range keys {
+		doc[i] = bson.E{Key: k, Value: normalizeDocument(m[k])}
+	}
+	return doc
+}
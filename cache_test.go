@@ -0,0 +1,77 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCachedFindMany(t *testing.T) {
+	type Post struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Title  string             `bson:"title"`
+		Status string             `bson:"status"`
+	}
+
+	db := setupMongoDB(t)
+
+	var finds int
+	repo := mongorepository.NewMongoRepository[Post](db, "posts",
+		mongorepository.WithQueryHook(func(op string, _ time.Duration, _ error) {
+			if op == "FindManyByFilter" {
+				finds++
+			}
+		}),
+	)
+
+	for _, title := range []string{"First", "Second"} {
+		_, err := repo.Create(context.Background(), Post{Title: title, Status: "published"})
+		require.NoError(t, err)
+	}
+
+	filter := mongorepository.Eq("status", "published")
+	ttl := 200 * time.Millisecond
+
+	posts, err := repo.CachedFindMany(context.Background(), ttl, 0, 10, filter)
+	require.NoError(t, err)
+	assert.Len(t, posts, 2)
+	assert.Equal(t, 1, finds)
+
+	// A new document doesn't show up until the cache expires
+	_, err = repo.Create(context.Background(), Post{Title: "Third", Status: "published"})
+	require.NoError(t, err)
+
+	posts, err = repo.CachedFindMany(context.Background(), ttl, 0, 10, mongorepository.Eq("status", "published"))
+	require.NoError(t, err)
+	assert.Len(t, posts, 2)
+	assert.Equal(t, 1, finds)
+
+	// Different paging is a different query
+	posts, err = repo.CachedFindMany(context.Background(), ttl, 1, 10, filter)
+	require.NoError(t, err)
+	assert.Len(t, posts, 2)
+	assert.Equal(t, 2, finds)
+
+	// A different sort order is a different query
+	ctx := mongorepository.WithQueryOptions(context.Background(),
+		mongorepository.WithSort(bson.D{{Key: "title", Value: -1}}),
+	)
+	posts, err = repo.CachedFindMany(ctx, ttl, 0, 10, filter)
+	require.NoError(t, err)
+	require.Len(t, posts, 3)
+	assert.Equal(t, "Third", posts[0].Title)
+	assert.Equal(t, 3, finds)
+
+	// The cache expires after the TTL
+	time.Sleep(ttl)
+	posts, err = repo.CachedFindMany(context.Background(), ttl, 0, 10, filter)
+	require.NoError(t, err)
+	assert.Len(t, posts, 3)
+	assert.Equal(t, 4, finds)
+}
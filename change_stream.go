@@ -0,0 +1,93 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChangeEvent is a change of a document in the collection, received by Watch.
+type ChangeEvent[T any] struct {
+	OperationType string   // Type of the change: insert, update, replace, delete, etc.
+	DocumentID    string   // ID of the changed document, ObjectIDs are converted into hex strings
+	Document      *T       // Current state of the document, nil for deletes
+	ResumeToken   bson.Raw // Token to resume watching right after this event, see WatchFrom
+}
+
+// changeEventDoc is the raw change event as returned by the change stream.
+type changeEventDoc[T any] struct {
+	OperationType string `bson:"operationType"`
+	FullDocument  *T     `bson:"fullDocument"`
+	DocumentKey   struct {
+		ID interface{} `bson:"_id"`
+	} `bson:"documentKey"`
+}
+
+// Watch calls fn for every change of the documents in the collection, i.e. inserts, updates, replaces and deletes.
+// The optional pipeline stages, e.g. $match on operationType, filter the change events.
+// Updated documents are looked up, so the event contains their current state.
+// It blocks until the context is cancelled, which is a normal termination, so it returns nil then.
+// If fn returns an error, watching stops and the error is returned.
+// Change streams require MongoDB to be deployed as a replica set or a sharded cluster.
+func (r *mongoRepository[T]) Watch(ctx context.Context, fn func(ChangeEvent[T]) error, pipeline ...bson.D) error {
+	return r.WatchFrom(ctx, nil, fn, pipeline...)
+}
+
+// WatchFrom works as Watch, but resumes watching right after the event with the given resume token,
+// e.g. stored from ChangeEvent.ResumeToken before a restart, so no events are missed.
+// If the resume token is nil, watching starts from the current moment.
+func (r *mongoRepository[T]) WatchFrom(ctx context.Context, resumeToken bson.Raw, fn func(ChangeEvent[T]) error, pipeline ...bson.D) (err error) {
+	ctx, op := r.startStreamingOperation(ctx, "Watch")
+	defer func() { op.end(err) }()
+
+	streamOpts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeToken != nil {
+		streamOpts.SetStartAfter(resumeToken)
+	}
+	stages := mongo.Pipeline{}
+	for _, stage := range pipeline {
+		stages = append(stages, stage)
+	}
+
+	stream, err := r.collection.Watch(ctx, stages, streamOpts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return errors.Join(ErrFailedToWatch, err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var doc changeEventDoc[T]
+		if err := stream.Decode(&doc); err != nil {
+			return errors.Join(ErrFailedToWatch, err)
+		}
+		event := ChangeEvent[T]{
+			OperationType: doc.OperationType,
+			Document:      doc.FullDocument,
+			ResumeToken:   append(bson.Raw(nil), stream.ResumeToken()...),
+		}
+		if oid, ok := doc.DocumentKey.ID.(primitive.ObjectID); ok {
+			event.DocumentID = oid.Hex()
+		} else if doc.DocumentKey.ID != nil {
+			event.DocumentID = fmt.Sprint(doc.DocumentKey.ID)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := stream.Err(); err != nil {
+		return errors.Join(ErrFailedToWatch, err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWatch(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	requireReplicaSet(t, db)
+
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	// Make sure the collection exists before watching it
+	_, err := repo.Create(context.Background(), User{Name: "Existing"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	created := make(chan string, 1)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		id, err := repo.Create(context.Background(), User{Name: "John Doe"})
+		assert.NoError(t, err)
+		created <- id
+	}()
+
+	var events []mongorepository.ChangeEvent[User]
+	err = repo.Watch(ctx, func(event mongorepository.ChangeEvent[User]) error {
+		events = append(events, event)
+		cancel()
+		return nil
+	}, bson.D{{Key: "$match", Value: bson.M{"operationType": "insert"}}})
+	require.NoError(t, err)
+	id := <-created
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "insert", events[0].OperationType)
+	assert.Equal(t, id, events[0].DocumentID)
+	require.NotNil(t, events[0].Document)
+	assert.Equal(t, "John Doe", events[0].Document.Name)
+	assert.NotEmpty(t, events[0].ResumeToken)
+
+	// Resume after the insert to receive the following delete
+	_, err = repo.Delete(context.Background(), id)
+	require.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var resumed mongorepository.ChangeEvent[User]
+	err = repo.WatchFrom(ctx, events[0].ResumeToken, func(event mongorepository.ChangeEvent[User]) error {
+		resumed = event
+		cancel()
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "delete", resumed.OperationType)
+	assert.Equal(t, id, resumed.DocumentID)
+	assert.Nil(t, resumed.Document)
+}
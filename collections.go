@@ -0,0 +1,171 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// namespaceExistsCode is the error code returned by MongoDB when the collection already exists.
+const namespaceExistsCode = 48
+
+// NewCappedRepository creates the capped collection, if it doesn't exist yet, and returns a repository for it.
+// A capped collection is a fixed-size ring buffer, e.g. for audit logs: once the sizeBytes or maxDocs limit
+// is reached, the oldest documents are removed to make room for the new ones. Pass 0 as maxDocs to limit by size only.
+// Note that documents in a capped collection can't be deleted one by one,
+// and updates that increase the size of a document fail.
+// If the collection already exists, its options are left unchanged.
+func NewCappedRepository[T any](ctx context.Context, db *mongo.Database, collectionName string, sizeBytes, maxDocs int64, opts ...Option) (*mongoRepository[T], error) {
+	collOpts := options.CreateCollection().SetCapped(true).SetSizeInBytes(sizeBytes)
+	if maxDocs > 0 {
+		collOpts.SetMaxDocuments(maxDocs)
+	}
+	if err := createCollection(ctx, db, collectionName, collOpts); err != nil {
+		return nil, err
+	}
+	return NewMongoRepository[T](db, collectionName, opts...), nil
+}
+
+// Time-series granularities, matching the interval between the measurements of the same source.
+const (
+	GranularitySeconds = "seconds"
+	GranularityMinutes = "minutes"
+	GranularityHours   = "hours"
+)
+
+// NewTimeSeriesRepository creates the time-series collection, if it doesn't exist yet, and returns a repository for it.
+// Time-series collections store measurements, e.g. metrics, efficiently: the documents with the same metaField value,
+// identifying their source, and close timeField values are stored together in compressed buckets.
+// The metaField is optional, and the granularity is one of GranularitySeconds, GranularityMinutes or GranularityHours,
+// or empty for the server default (seconds); an invalid one results in an error with the ErrInvalidGranularity error code.
+// It requires MongoDB 5.0 or later. Note that the measurements are meant to be append-only:
+// updates may only modify the metaField, deletes may only filter on it before MongoDB 7.0,
+// and unique indexes, e.g. the one used by CreateIdempotent, are not supported.
+// If the collection already exists, its options are left unchanged.
+func NewTimeSeriesRepository[T any](ctx context.Context, db *mongo.Database, collectionName, timeField, metaField, granularity string, opts ...Option) (*mongoRepository[T], error) {
+	tsOpts := options.TimeSeries().SetTimeField(timeField)
+	if metaField != "" {
+		tsOpts.SetMetaField(metaField)
+	}
+	switch granularity {
+	case "":
+	case GranularitySeconds, GranularityMinutes, GranularityHours:
+		tsOpts.SetGranularity(granularity)
+	default:
+		return nil, errors.Join(ErrFailedToCreateCollection, ErrInvalidGranularity, fmt.Errorf("unsupported granularity %q", granularity))
+	}
+
+	if err := createCollection(ctx, db, collectionName, options.CreateCollection().SetTimeSeriesOptions(tsOpts)); err != nil {
+		return nil, err
+	}
+	return NewMongoRepository[T](db, collectionName, opts...), nil
+}
+
+// createCollection creates the collection with the given options, ignoring the error if it already exists.
+func createCollection(ctx context.Context, db *mongo.Database, collectionName string, opts *options.CreateCollectionOptions) error {
+	if err := db.CreateCollection(ctx, collectionName, opts); err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.HasErrorCode(namespaceExistsCode) {
+			return nil
+		}
+		return errors.Join(ErrFailedToCreateCollection, err)
+	}
+	return nil
+}
+
+// tailRetryInterval is the delay before reopening a dead tailable cursor, e.g. on an empty collection.
+const tailRetryInterval = 100 * time.Millisecond
+
+// Tail follows a capped collection in real time, like "tail -f": it calls fn for every document
+// matching the filters, first for the existing ones in insertion order, then for the new ones as they arrive.
+// It blocks until the context is cancelled, which is a normal termination, so it returns nil then.
+// If fn returns an error, tailing stops and the error is returned.
+// It works on capped collections only, see NewCappedRepository.
+func (r *mongoRepository[T]) Tail(ctx context.Context, fn func(T) error, filters ...FilterFunc) (err error) {
+	ctx, op := r.startStreamingOperation(ctx, "Tail")
+	defer func() { op.end(err) }()
+
+	var lastID interface{}
+	for {
+		filter := bson.D{}
+		for _, f := range filters {
+			filter = f(filter)
+		}
+		// Resume after the last seen document when the cursor is reopened
+		if lastID != nil {
+			filter = append(filter, bson.E{Key: "_id", Value: bson.M{"$gt": lastID}})
+		}
+
+		findOptions := options.Find().SetCursorType(options.TailableAwait)
+		cursor, err := r.collection.Find(ctx, filter, findOptions)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+
+		for cursor.Next(ctx) {
+			var element T
+			if err := cursor.Decode(&element); err != nil {
+				cursor.Close(ctx)
+				return errors.Join(ErrFailedToFindManyByFilter, err)
+			}
+			// The cursor reuses its buffer, so the id must be copied
+			id := cursor.Current.Lookup("_id")
+			lastID = bson.RawValue{Type: id.Type, Value: append([]byte(nil), id.Value...)}
+			if err := fn(element); err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+
+		// The cursor is dead, e.g. the collection was empty, so wait before reopening it
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(tailRetryInterval):
+		}
+	}
+}
+
+// CollStats holds the storage statistics of a collection, e.g. for capacity planning.
+// The sizes are in bytes.
+type CollStats struct {
+	Count          int64            `bson:"count"`          // Number of documents
+	Size           int64            `bson:"size"`           // Uncompressed size of the documents
+	StorageSize    int64            `bson:"storageSize"`    // Storage allocated for the documents, compressed
+	TotalIndexSize int64            `bson:"totalIndexSize"` // Total size of the indexes
+	IndexSizes     map[string]int64 `bson:"indexSizes"`     // Size of each index by its name
+	AvgObjSize     float64          `bson:"avgObjSize"`     // Average size of a document, zero for an empty collection
+}
+
+// Stats returns the storage statistics of the collection by running the collStats command.
+// The number of documents is taken from the collection metadata, so it's cheap but may be
+// inaccurate after an unclean shutdown; use Count for an exact number.
+// It returns an error with the ErrFailedToGetStats error code if the command fails.
+func (r *mongoRepository[T]) Stats(ctx context.Context) (_ CollStats, err error) {
+	ctx, op := r.startOperation(ctx, "Stats")
+	defer func() { op.end(err) }()
+
+	var stats CollStats
+	cmd := bson.D{{Key: "collStats", Value: r.collection.Name()}}
+	if err := r.collection.Database().RunCommand(ctx, cmd).Decode(&stats); err != nil {
+		return CollStats{}, errors.Join(ErrFailedToGetStats, err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,169 @@
+package mongorepository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCappedRepository(t *testing.T) {
+	type AuditLog struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Action    string             `bson:"action"`
+		CreatedAt time.Time          `bson:"created_at"`
+	}
+
+	db := setupMongoDB(t)
+
+	repo, err := mongorepository.NewCappedRepository[AuditLog](context.Background(), db, "audit_logs", 1024*1024, 3)
+	require.NoError(t, err)
+
+	// Creating it again is not an error
+	_, err = mongorepository.NewCappedRepository[AuditLog](context.Background(), db, "audit_logs", 1024*1024, 3)
+	require.NoError(t, err)
+
+	specs, err := db.ListCollectionSpecifications(context.Background(), bson.M{"name": "audit_logs"})
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+
+	var collOpts struct {
+		Capped bool  `bson:"capped"`
+		Size   int64 `bson:"size"`
+		Max    int64 `bson:"max"`
+	}
+	require.NoError(t, bson.Unmarshal(specs[0].Options, &collOpts))
+	assert.True(t, collOpts.Capped)
+	assert.Equal(t, int64(1024*1024), collOpts.Size)
+	assert.Equal(t, int64(3), collOpts.Max)
+
+	// The oldest documents are removed once the limit is reached
+	for _, action := range []string{"login", "view", "edit", "logout"} {
+		_, err := repo.Create(context.Background(), AuditLog{Action: action, CreatedAt: time.Now()})
+		require.NoError(t, err)
+	}
+	logs, err := repo.FindManyByFilter(context.Background(), 0, 0)
+	require.NoError(t, err)
+	require.Len(t, logs, 3)
+	assert.Equal(t, "view", logs[0].Action)
+}
+
+func TestTail(t *testing.T) {
+	type AuditLog struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Action string             `bson:"action"`
+	}
+
+	db := setupMongoDB(t)
+
+	repo, err := mongorepository.NewCappedRepository[AuditLog](context.Background(), db, "audit_logs", 1024*1024, 0)
+	require.NoError(t, err)
+
+	actions := []string{"login", "view", "edit", "logout"}
+
+	// Insert the documents while tailing
+	go func() {
+		for _, action := range actions {
+			time.Sleep(50 * time.Millisecond)
+			_, err := repo.Create(context.Background(), AuditLog{Action: action})
+			assert.NoError(t, err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var received []string
+	err = repo.Tail(ctx, func(log AuditLog) error {
+		received = append(received, log.Action)
+		if len(received) == len(actions) {
+			cancel()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, actions, received)
+}
+
+func TestStats(t *testing.T) {
+	type User struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Email string             `bson:"email"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	require.NoError(t, repo.CreateIndex(context.Background(), "email", mongorepository.Unique(true)))
+	for i := 0; i < 10; i++ {
+		_, err := repo.Create(context.Background(), User{Email: fmt.Sprintf("user%d@example.com", i)})
+		require.NoError(t, err)
+	}
+
+	stats, err := repo.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), stats.Count)
+	assert.Positive(t, stats.Size)
+	assert.GreaterOrEqual(t, stats.StorageSize, int64(0))
+	assert.Positive(t, stats.TotalIndexSize)
+	assert.Positive(t, stats.AvgObjSize)
+	assert.Contains(t, stats.IndexSizes, "_id_")
+	assert.Contains(t, stats.IndexSizes, "email_1")
+}
+
+func TestTimeSeriesRepository(t *testing.T) {
+	type Measurement struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Timestamp time.Time          `bson:"timestamp"`
+		Sensor    string             `bson:"sensor"`
+		Value     float64            `bson:"value"`
+	}
+
+	db := setupMongoDB(t)
+
+	repo, err := mongorepository.NewTimeSeriesRepository[Measurement](context.Background(), db, "measurements",
+		"timestamp", "sensor", mongorepository.GranularityMinutes)
+	require.NoError(t, err)
+
+	// Creating it again is not an error
+	_, err = mongorepository.NewTimeSeriesRepository[Measurement](context.Background(), db, "measurements",
+		"timestamp", "sensor", mongorepository.GranularityMinutes)
+	require.NoError(t, err)
+
+	specs, err := db.ListCollectionSpecifications(context.Background(), bson.M{"name": "measurements"})
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	assert.Equal(t, "timeseries", specs[0].Type)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		_, err := repo.Create(context.Background(), Measurement{
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+			Sensor:    "s1",
+			Value:     float64(i),
+		})
+		require.NoError(t, err)
+	}
+
+	measurements, err := repo.FindManyByFilter(context.Background(), 0, 0,
+		mongorepository.Between("timestamp", start.Add(2*time.Minute), start.Add(4*time.Minute)),
+		mongorepository.Eq("sensor", "s1"),
+	)
+	require.NoError(t, err)
+	require.Len(t, measurements, 3)
+	for _, m := range measurements {
+		assert.GreaterOrEqual(t, m.Value, 2.0)
+		assert.LessOrEqual(t, m.Value, 4.0)
+	}
+
+	t.Run("InvalidGranularity", func(t *testing.T) {
+		_, err := mongorepository.NewTimeSeriesRepository[Measurement](context.Background(), db, "other_measurements",
+			"timestamp", "sensor", "days")
+		require.ErrorIs(t, err, mongorepository.ErrInvalidGranularity)
+	})
+}
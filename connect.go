@@ -0,0 +1,60 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ConnectWithRetry connects to MongoDB and pings it until it succeeds or the attempts are exhausted,
+// waiting for backoff between attempts. It makes the application startup resilient to MongoDB
+// becoming ready later, e.g. in container orchestration.
+// It returns the database with the given name and an error with the ErrFailedToConnect error code,
+// if all attempts failed or the context is done.
+func ConnectWithRetry(ctx context.Context, uri, dbName string, attempts int, backoff time.Duration) (*mongo.Database, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		if err == nil {
+			if err = client.Ping(ctx, nil); err == nil {
+				return client.Database(dbName), nil
+			}
+			_ = client.Disconnect(context.Background())
+		}
+		lastErr = err
+
+		if attempt == attempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(ErrFailedToConnect, ctx.Err(), lastErr)
+		case <-time.After(backoff):
+		}
+	}
+
+	return nil, errors.Join(ErrFailedToConnect, fmt.Errorf("gave up after %d attempts: %w", attempts, lastErr))
+}
+
+// Ping verifies the connection to the database of the repository by running the cheap ping command,
+// e.g. for readiness probes. Unlike the client's Ping, it goes through the repository database,
+// so the default timeout, the query hook and tracing apply to it.
+// It returns an error with the ErrFailedToConnect error code if the database can't be reached.
+func (r *mongoRepository[T]) Ping(ctx context.Context) (err error) {
+	ctx, op := r.startOperation(ctx, "Ping")
+	defer func() { op.end(err) }()
+
+	if err := r.collection.Database().RunCommand(ctx, bson.D{{Key: "ping", Value: 1}}).Err(); err != nil {
+		return errors.Join(ErrFailedToConnect, err)
+	}
+	return nil
+}
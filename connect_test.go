@@ -0,0 +1,55 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestConnectWithRetry(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		conn, err := mongorepository.ConnectWithRetry(context.Background(), getMongoDBURI(), "test_db", 3, 10*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, "test_db", conn.Name())
+		require.NoError(t, conn.Client().Disconnect(context.Background()))
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		uri := "mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=50&connectTimeoutMS=50"
+
+		start := time.Now()
+		_, err := mongorepository.ConnectWithRetry(context.Background(), uri, "test_db", 3, 20*time.Millisecond)
+		require.ErrorIs(t, err, mongorepository.ErrFailedToConnect)
+		assert.Contains(t, err.Error(), "gave up after 3 attempts")
+		// 3 attempts with 2 backoff delays in between
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	})
+}
+
+func TestPing(t *testing.T) {
+	type User struct {
+		Name string `bson:"name"`
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		db := setupMongoDB(t)
+		repo := mongorepository.NewMongoRepository[User](db, "users")
+		require.NoError(t, repo.Ping(context.Background()))
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		uri := "mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=50&connectTimeoutMS=50"
+		client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+
+		repo := mongorepository.NewMongoRepository[User](client.Database("test_db"), "users")
+		require.ErrorIs(t, repo.Ping(context.Background()), mongorepository.ErrFailedToConnect)
+	})
+}
@@ -4,17 +4,46 @@ import "errors"
 
 // Predefined errors
 var (
-	ErrNotFound                 = errors.New("document not found")
-	ErrDuplicate                = errors.New("document already exists")
-	ErrFailedToFindByID         = errors.New("failed to find document by id")
-	ErrFailedToFindByIDs        = errors.New("failed to find documents by ids")
-	ErrInvalidDocumentID        = errors.New("invalid document id")
-	ErrFailedToCreate           = errors.New("failed to create document")
-	ErrFailedToUpdate           = errors.New("failed to update document")
-	ErrFailedToUpdateMany       = errors.New("failed to update documents")
-	ErrFailedToDelete           = errors.New("failed to delete document")
-	ErrFailedToFindOneByFilter  = errors.New("failed to find a document by the given filter")
-	ErrFailedToFindManyByFilter = errors.New("failed to find any documents by the given filter")
-	ErrFailedToCreateIndex      = errors.New("failed to create collection index")
-	ErrFailedToDeleteMany       = errors.New("failed to delete documents")
+	ErrSchemaMismatch             = errors.New("document does not match the collection schema")
+	ErrValidation                 = errors.New("document validation failed")
+	ErrFailedToConnect            = errors.New("failed to connect to database")
+	ErrNotFound                   = errors.New("document not found")
+	ErrDocumentTooLarge           = errors.New("document exceeds the maximum BSON document size")
+	ErrDuplicate                  = errors.New("document already exists")
+	ErrFailedToFindByID           = errors.New("failed to find document by id")
+	ErrFailedToFindByIDs          = errors.New("failed to find documents by ids")
+	ErrInvalidDocumentID          = errors.New("invalid document id")
+	ErrFailedToCreate             = errors.New("failed to create document")
+	ErrFailedToUpdate             = errors.New("failed to update document")
+	ErrVersionConflict            = errors.New("document version conflict")
+	ErrFailedToReplace            = errors.New("failed to replace document")
+	ErrFailedToUpdateMany         = errors.New("failed to update documents")
+	ErrFailedToDelete             = errors.New("failed to delete document")
+	ErrFailedToFindOneByFilter    = errors.New("failed to find a document by the given filter")
+	ErrFailedToFindManyByFilter   = errors.New("failed to find any documents by the given filter")
+	ErrFailedToCreateCollection   = errors.New("failed to create collection")
+	ErrFailedToCreateIndex        = errors.New("failed to create collection index")
+	ErrInvalidGranularity         = errors.New("invalid granularity")
+	ErrInvalidNormalizeMode       = errors.New("invalid string normalization mode")
+	ErrInvalidSliceSize           = errors.New("slice size must be positive")
+	ErrFailedToDeleteMany         = errors.New("failed to delete documents")
+	ErrLimitExceeded              = errors.New("requested limit exceeds the max limit")
+	ErrNoUpdateOps                = errors.New("no update operations provided")
+	ErrNoIndexKeys                = errors.New("no index keys provided")
+	ErrFailedToListIndexes        = errors.New("failed to list collection indexes")
+	ErrFailedToDropIndex          = errors.New("failed to drop collection index")
+	ErrFailedToAggregate          = errors.New("failed to aggregate documents")
+	ErrFailedToWatch              = errors.New("failed to watch collection changes")
+	ErrFailedToGetStats           = errors.New("failed to get collection stats")
+	ErrFailedToExplain            = errors.New("failed to explain query")
+	ErrFailedToStartSession       = errors.New("failed to start session")
+	ErrFailedToExecuteTransaction = errors.New("failed to execute transaction")
+	ErrFailedToBulkWrite          = errors.New("failed to execute bulk write")
+	ErrInvalidWriteOp             = errors.New("invalid bulk write operation")
+	ErrTextIndexExists            = errors.New("collection already has a text index")
+	ErrNotSupported               = errors.New("operation not supported by the in-memory repository")
+	ErrUnsupportedFilter          = errors.New("filter not supported by the in-memory repository")
+	ErrNoTextIndex                = errors.New("no text index found, call CreateFullTextIndex before searching")
+	ErrTooManySearchOptions       = errors.New("at most one SearchOptions can be passed")
+	ErrGroupKeyCollision          = errors.New("distinct group values have the same string form")
 )
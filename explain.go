@@ -0,0 +1,49 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Explain returns the query plan of a find with the given filters, as the explain command
+// with the "executionStats" verbosity reports it, to diagnose slow queries without dropping to the shell.
+// The sort, collation and hint query options carried by the context are applied to the explained query,
+// so e.g. the winning plan of a query with WithHint can be inspected.
+// Note that the query is actually executed to collect the execution statistics.
+// The function returns the raw explain result and an error, if any.
+func (r *mongoRepository[T]) Explain(ctx context.Context, filters ...FilterFunc) (_ bson.M, err error) {
+	ctx, op := r.startOperation(ctx, "Explain")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	find := bson.D{
+		{Key: "find", Value: r.collection.Name()},
+		{Key: "filter", Value: filter},
+	}
+	qopts := queryOptionsFromContext(ctx)
+	if qopts.sort != nil {
+		find = append(find, bson.E{Key: "sort", Value: qopts.sort})
+	}
+	if qopts.collation != nil {
+		find = append(find, bson.E{Key: "collation", Value: qopts.collation.ToDocument()})
+	}
+	if qopts.hint != "" {
+		find = append(find, bson.E{Key: "hint", Value: qopts.hint})
+	}
+
+	cmd := bson.D{
+		{Key: "explain", Value: find},
+		{Key: "verbosity", Value: "executionStats"},
+	}
+	var result bson.M
+	if err := r.collection.Database().RunCommand(ctx, cmd).Decode(&result); err != nil {
+		return nil, errors.Join(ErrFailedToExplain, err)
+	}
+	return result, nil
+}
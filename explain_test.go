@@ -0,0 +1,75 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExplain(t *testing.T) {
+	type User struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Name   string             `bson:"name"`
+		Status string             `bson:"status"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	require.NoError(t, repo.CreateIndex(context.Background(), "status", mongorepository.Name("status_idx")))
+
+	for _, name := range []string{"John", "Jane"} {
+		_, err := repo.Create(context.Background(), User{Name: name, Status: "active"})
+		require.NoError(t, err)
+	}
+
+	t.Run("Plan", func(t *testing.T) {
+		plan, err := repo.Explain(context.Background(), mongorepository.Eq("name", "John"))
+		require.NoError(t, err)
+		require.NotEmpty(t, plan)
+		assert.Contains(t, plan, "queryPlanner")
+
+		stats, ok := plan["executionStats"].(bson.M)
+		require.True(t, ok)
+		assert.EqualValues(t, 1, stats["nReturned"])
+	})
+
+	t.Run("Hint", func(t *testing.T) {
+		ctx := mongorepository.WithQueryOptions(context.Background(), mongorepository.WithHint("status_idx"))
+		plan, err := repo.Explain(ctx, mongorepository.Eq("name", "John"))
+		require.NoError(t, err)
+
+		// The hinted index is used even though it doesn't help the filter
+		planner, ok := plan["queryPlanner"].(bson.M)
+		require.True(t, ok)
+		assert.Contains(t, fmtPlan(planner["winningPlan"]), "status_idx")
+	})
+}
+
+// fmtPlan returns the extended JSON representation of the plan, to search for stage fields in it.
+func fmtPlan(plan interface{}) string {
+	data, err := bson.MarshalExtJSON(bson.M{"plan": plan}, false, false)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// toInt64 normalizes a numeric explain output value, which may be encoded as int32, int64 or double
+// depending on the server version.
+func toInt64(t testing.TB, value interface{}) int64 {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	t.Fatalf("unexpected numeric type %T", value)
+	return 0
+}
@@ -1,6 +1,12 @@
 package mongorepository
 
 import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -14,6 +20,30 @@ func Eq(field string, value interface{}) FilterFunc {
 	}
 }
 
+// Field joins the parts of a nested field path with dots, e.g. Field("address", "city") returns "address.city".
+func Field(parts ...string) string {
+	return strings.Join(parts, ".")
+}
+
+// NestedEq creates an equality filter on a field of an embedded document, given by the parts of its path,
+// e.g. NestedEq([]string{"address", "city"}, "Berlin") produces {"address.city": "Berlin"}.
+// Unlike an equality filter on the whole embedded document, which requires an exact match of all its fields
+// in the same order, it matches the documents by the single nested field only.
+func NestedEq(path []string, value interface{}) FilterFunc {
+	return Eq(Field(path...), value)
+}
+
+// EqDoc creates a filter matching documents where the embedded document field is exactly equal to doc,
+// e.g. EqDoc("address", bson.D{{Key: "city", Value: "Berlin"}, {Key: "zip", Value: "10115"}}).
+// MongoDB compares embedded documents as a whole: they must have the same fields with the same values
+// in the same order, so a document with an extra field or the fields in another order doesn't match.
+// Pass a bson.D or a struct to control the field order; a bson.M has no defined order, so it's only safe
+// for single-field documents. To match by some of the fields of the embedded document, use
+// dot notation instead, e.g. Eq("address.city", "Berlin") or NestedEq.
+func EqDoc(field string, doc interface{}) FilterFunc {
+	return Eq(field, doc)
+}
+
 // Gt creates a greater-than filter
 func Gt(field string, value interface{}) FilterFunc {
 	return func(filter bson.D) bson.D {
@@ -38,10 +68,9 @@ func In(field string, values interface{}) FilterFunc {
 // And combines multiple filters with a logical AND
 func And(filters ...FilterFunc) FilterFunc {
 	return func(filter bson.D) bson.D {
-		andFilters := make([]bson.E, 0)
+		andFilters := make(bson.A, 0, len(filters))
 		for _, f := range filters {
-			andFilter := f(bson.D{})
-			andFilters = append(andFilters, andFilter...)
+			andFilters = append(andFilters, f(bson.D{}))
 		}
 		return append(filter, bson.E{Key: "$and", Value: andFilters})
 	}
@@ -50,10 +79,9 @@ func And(filters ...FilterFunc) FilterFunc {
 // Or combines multiple filters with a logical OR
 func Or(filters ...FilterFunc) FilterFunc {
 	return func(filter bson.D) bson.D {
-		orFilters := make([]bson.E, 0)
+		orFilters := make(bson.A, 0, len(filters))
 		for _, f := range filters {
-			orFilter := f(bson.D{})
-			orFilters = append(orFilters, orFilter...)
+			orFilters = append(orFilters, f(bson.D{}))
 		}
 		return append(filter, bson.E{Key: "$or", Value: orFilters})
 	}
@@ -87,6 +115,62 @@ func Exists(field string, exists bool) FilterFunc {
 	}
 }
 
+// IsNull creates a filter matching documents where the field is null or missing,
+// the same way as Eq(field, nil). To tell the two cases apart, use IsMissing for the missing fields
+// and Where(bson.M{field: bson.M{"$type": "null"}}) for the fields explicitly set to null.
+func IsNull(field string) FilterFunc {
+	return func(filter bson.D) bson.D {
+		return append(filter, bson.E{Key: field, Value: bson.M{"$in": bson.A{nil}}})
+	}
+}
+
+// IsMissing creates a filter matching documents without the field, unlike IsNull,
+// which also matches documents with the field explicitly set to null.
+// It is a shortcut for Exists(field, false).
+func IsMissing(field string) FilterFunc {
+	return Exists(field, false)
+}
+
+// NestedExists checks if a field exists at a dotted path which may traverse nested arrays, e.g. "orders.items.sku".
+// MongoDB resolves each path segment against every element of an array it meets, so:
+//   - exists=true matches documents where at least one element along the path has the field,
+//     even if the other elements don't have it or it is set to null;
+//   - exists=false matches documents where no element has the field, including documents
+//     missing an intermediate field or having an empty array along the path.
+//
+// To match documents where some element misses the field, use a raw $elemMatch condition with Where instead.
+func NestedExists(path string, exists bool) FilterFunc {
+	return func(filter bson.D) bson.D {
+		return append(filter, bson.E{Key: path, Value: bson.M{"$exists": exists}})
+	}
+}
+
+// All creates a filter matching array values containing every one of the given values, in any order
+func All(field string, values interface{}) FilterFunc {
+	return func(filter bson.D) bson.D {
+		return append(filter, bson.E{Key: field, Value: bson.M{"$all": values}})
+	}
+}
+
+// Size creates a filter matching array values with exactly n elements.
+// The $size operator doesn't support ranges, e.g. "at least 3 elements"; use Where with
+// {$expr: {$gte: [{$size: "$field"}, 3]}} or Exists("field.2", true) for them instead.
+func Size(field string, n int) FilterFunc {
+	return func(filter bson.D) bson.D {
+		return append(filter, bson.E{Key: field, Value: bson.M{"$size": n}})
+	}
+}
+
+// NotEmpty creates a filter matching documents where the array field exists and has at least one element,
+// using {field.0: {$exists: true}}, which, unlike $size, can use an index on the field.
+// Missing fields, nulls and empty arrays don't match. Note that it also matches
+// embedded documents having a field named "0", since MongoDB resolves the path against them as well.
+func NotEmpty(field string) FilterFunc {
+	return func(filter bson.D) bson.D {
+		return append(filter, bson.E{Key: field + ".0", Value: bson.M{"$exists": true}})
+	}
+}
+
 // Regex creates a filter for regular expression matching
 func Regex(field string, pattern string, options string) FilterFunc {
 	return func(filter bson.D) bson.D {
@@ -94,9 +178,278 @@ func Regex(field string, pattern string, options string) FilterFunc {
 	}
 }
 
+// RegexCompiled creates a filter for regular expression matching with a compiled Go regexp,
+// so the pattern is validated at compile time. The leading flags group of the pattern, e.g. (?im),
+// is translated into MongoDB regex options: i, m and s keep their meaning. Other flags, e.g. U,
+// and scoped flag groups, e.g. (?i:abc), are left in the pattern, since MongoDB supports them inline.
+// Note that the pattern is evaluated by the MongoDB (PCRE) regex engine, not by the Go one.
+func RegexCompiled(field string, re *regexp.Regexp) FilterFunc {
+	pattern, options := splitRegexFlags(re.String())
+	return Regex(field, pattern, options)
+}
+
+// splitRegexFlags splits the leading flags group off the pattern and returns the pattern
+// and the MongoDB regex options translated from the flags.
+func splitRegexFlags(pattern string) (string, string) {
+	if !strings.HasPrefix(pattern, "(?") {
+		return pattern, ""
+	}
+	end := strings.IndexByte(pattern, ')')
+	if end < 0 {
+		return pattern, ""
+	}
+	flags := pattern[2:end]
+	if flags == "" || strings.Trim(flags, "imsU") != "" {
+		// Not a flags group, e.g. a named or a scoped group
+		return pattern, ""
+	}
+
+	var options, rest strings.Builder
+	for _, flag := range flags {
+		if flag == 'U' {
+			rest.WriteRune(flag)
+		} else if !strings.ContainsRune(options.String(), flag) {
+			options.WriteRune(flag)
+		}
+	}
+	pattern = pattern[end+1:]
+	if rest.Len() > 0 {
+		pattern = "(?" + rest.String() + ")" + pattern
+	}
+	return pattern, options.String()
+}
+
+// StartsWith creates a case-insensitive filter matching string values starting with the prefix.
+// The prefix is escaped, so regex metacharacters in it are matched literally.
+func StartsWith(field, prefix string) FilterFunc {
+	return Regex(field, "^"+regexp.QuoteMeta(prefix), "i")
+}
+
+// Contains creates a case-insensitive filter matching string values containing the substring.
+// The substring is escaped, so regex metacharacters in it are matched literally.
+func Contains(field, substr string) FilterFunc {
+	return Regex(field, regexp.QuoteMeta(substr), "i")
+}
+
+// EndsWith creates a case-insensitive filter matching string values ending with the suffix.
+// The suffix is escaped, so regex metacharacters in it are matched literally.
+func EndsWith(field, suffix string) FilterFunc {
+	return Regex(field, regexp.QuoteMeta(suffix)+"$", "i")
+}
+
+// PrefixIndexed creates a case-sensitive filter matching string values starting with the prefix
+// with a range query, {field: {$gte: prefix, $lt: upper bound}}, e.g. for autocomplete.
+// Unlike StartsWith, whose case-insensitive regex has to examine every key of the index, the range
+// only examines the index keys having the prefix. The upper bound is the prefix with its last character
+// incremented, e.g. "abd" for "abc", so unlike the common prefix+"\uffff" bound it also matches values
+// continuing with characters beyond U+FFFF, e.g. emojis. An empty prefix matches all string values.
+// Note that the range uses the collation of the query, so don't combine it with a case-insensitive collation.
+func PrefixIndexed(field, prefix string) FilterFunc {
+	upper, bounded := prefixUpperBound(prefix)
+	return func(filter bson.D) bson.D {
+		// Range operators only match values of the same type, so {$gte: ""} matches all strings
+		cond := bson.M{"$gte": prefix}
+		if bounded {
+			cond["$lt"] = upper
+		}
+		return append(filter, bson.E{Key: field, Value: cond})
+	}
+}
+
+// prefixUpperBound returns the smallest string greater than all strings starting with the prefix,
+// comparing them by their UTF-8 bytes the same way as MongoDB without collation.
+// It reports false if there is no such string, e.g. for an empty prefix.
+func prefixUpperBound(prefix string) (string, bool) {
+	runes := []rune(prefix)
+	for i := len(runes) - 1; i >= 0; i-- {
+		next := runes[i] + 1
+		if next >= 0xD800 && next <= 0xDFFF {
+			// Skip the surrogates, which aren't valid in UTF-8
+			next = 0xE000
+		}
+		if next <= utf8.MaxRune {
+			return string(runes[:i]) + string(next), true
+		}
+	}
+	return "", false
+}
+
 // TextSearch creates a full-text search filter
 func TextSearch(searchTerm string) FilterFunc {
 	return func(filter bson.D) bson.D {
 		return append(filter, bson.E{Key: "$text", Value: bson.M{"$search": searchTerm}})
 	}
 }
+
+// Between creates a range filter matching values between lo and hi, both bounds inclusive
+func Between(field string, lo, hi interface{}) FilterFunc {
+	return func(filter bson.D) bson.D {
+		return append(filter, bson.E{Key: field, Value: bson.M{"$gte": lo, "$lte": hi}})
+	}
+}
+
+// BetweenExclusive creates a range filter matching values between lo and hi, both bounds exclusive
+func BetweenExclusive(field string, lo, hi interface{}) FilterFunc {
+	return func(filter bson.D) bson.D {
+		return append(filter, bson.E{Key: field, Value: bson.M{"$gt": lo, "$lt": hi}})
+	}
+}
+
+// AnyEq creates a filter matching documents where the field equals any of the given values.
+// It is a shortcut for In with variadic values.
+func AnyEq(field string, values ...interface{}) FilterFunc {
+	return In(field, values)
+}
+
+// Not negates the conditions created by the given filter.
+// Field conditions with operators are wrapped with $not, e.g. Not(Regex("name", "^a", "i"))
+// produces {name: {$not: {$regex: "^a", $options: "i"}}}. Equality conditions are turned into $ne,
+// and logical operators, e.g. $or or $and, are wrapped with $nor.
+// Note that, like in MongoDB, negated conditions also match documents missing the field.
+func Not(f FilterFunc) FilterFunc {
+	return func(filter bson.D) bson.D {
+		for _, e := range f(bson.D{}) {
+			if strings.HasPrefix(e.Key, "$") {
+				filter = append(filter, bson.E{Key: "$nor", Value: bson.A{bson.D{e}}})
+				continue
+			}
+			if cond, ok := e.Value.(bson.M); ok {
+				filter = append(filter, bson.E{Key: e.Key, Value: bson.M{"$not": cond}})
+				continue
+			}
+			filter = append(filter, bson.E{Key: e.Key, Value: bson.M{"$ne": e.Value}})
+		}
+		return filter
+	}
+}
+
+// SameDay creates a filter matching documents where the date field falls on the same calendar day as day
+// in the given timezone, e.g. "all events from today" in the user's timezone.
+// The timezone is an Olson timezone identifier, e.g. "Europe/Berlin", or a UTC offset, e.g. "+03:00";
+// an empty timezone means UTC. Both the field and day are truncated to the start of the day by the server
+// with $dateTrunc in the same timezone, so the day boundaries, including DST transitions
+// and days not starting at midnight, are always computed consistently.
+// It requires MongoDB 5.0 or later and can't use indexes, so combine it with a range filter on large collections.
+func SameDay(field string, day time.Time, timezone string) FilterFunc {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	return func(filter bson.D) bson.D {
+		return append(filter, bson.E{Key: "$expr", Value: bson.M{"$eq": bson.A{
+			bson.M{"$dateTrunc": bson.M{"date": "$" + field, "unit": "day", "timezone": timezone}},
+			bson.M{"$dateTrunc": bson.M{"date": day, "unit": "day", "timezone": timezone}},
+		}}})
+	}
+}
+
+// OnDate creates a filter matching documents where the date field falls on the calendar day of day in loc,
+// using the half-open range [start of the day, start of the next day). A nil loc means UTC.
+// The day is taken from day converted to loc, e.g. 2024-03-10 23:30 UTC is 2024-03-11 in Europe/Berlin.
+// Unlike SameDay, the day boundaries are computed by the client, so the filter can use an index on the field,
+// and the days affected by DST transitions correctly span 23 or 25 hours.
+func OnDate(field string, day time.Time, loc *time.Location) FilterFunc {
+	if loc == nil {
+		loc = time.UTC
+	}
+	y, m, d := day.In(loc).Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	end := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	return func(filter bson.D) bson.D {
+		return append(filter, bson.E{Key: field, Value: bson.M{"$gte": start, "$lt": end}})
+	}
+}
+
+// Expr creates a filter with an aggregation expression, which can compare fields of the same document,
+// e.g. Expr(bson.M{"$gt": bson.A{"$spent", "$budget"}}) produces {$expr: {$gt: ["$spent", "$budget"]}}.
+// If the filter already has an $expr condition, both must hold, so the expression is added to a top-level $and instead.
+// Note that $expr conditions can only use indexes for equality comparisons with constant values.
+func Expr(doc bson.M) FilterFunc {
+	return func(filter bson.D) bson.D {
+		return appendCondition(filter, bson.E{Key: "$expr", Value: doc})
+	}
+}
+
+// ExprEq creates a filter matching documents where the value of fieldA equals the value of fieldB
+func ExprEq(fieldA, fieldB string) FilterFunc {
+	return compareFields("$eq", fieldA, fieldB)
+}
+
+// ExprNe creates a filter matching documents where the value of fieldA doesn't equal the value of fieldB
+func ExprNe(fieldA, fieldB string) FilterFunc {
+	return compareFields("$ne", fieldA, fieldB)
+}
+
+// ExprGt creates a filter matching documents where the value of fieldA is greater than the value of fieldB
+func ExprGt(fieldA, fieldB string) FilterFunc {
+	return compareFields("$gt", fieldA, fieldB)
+}
+
+// ExprGte creates a filter matching documents where the value of fieldA is greater than or equal to the value of fieldB
+func ExprGte(fieldA, fieldB string) FilterFunc {
+	return compareFields("$gte", fieldA, fieldB)
+}
+
+// ExprLt creates a filter matching documents where the value of fieldA is less than the value of fieldB
+func ExprLt(fieldA, fieldB string) FilterFunc {
+	return compareFields("$lt", fieldA, fieldB)
+}
+
+// ExprLte creates a filter matching documents where the value of fieldA is less than or equal to the value of fieldB
+func ExprLte(fieldA, fieldB string) FilterFunc {
+	return compareFields("$lte", fieldA, fieldB)
+}
+
+// compareFields creates an $expr filter comparing the values of two fields of the same document with the operator.
+// Like in aggregation expressions, a missing field is compared as null, which is less than any other value.
+func compareFields(operator, fieldA, fieldB string) FilterFunc {
+	return Expr(bson.M{operator: bson.A{"$" + fieldA, "$" + fieldB}})
+}
+
+// Where merges an arbitrary raw filter document into the filter, as an escape hatch for operators
+// not covered by the typed filters, e.g. $expr or $mod. It composes with the other filters, including And and Or.
+// The raw conditions never overwrite the ones added by other filters: if a key is already present in the filter,
+// both conditions must hold, so the raw one is added to a top-level $and instead.
+func Where(doc bson.M) FilterFunc {
+	return func(filter bson.D) bson.D {
+		// Sort the keys, since the iteration order of maps is random
+		keys := make([]string, 0, len(doc))
+		for k := range doc {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			filter = appendCondition(filter, bson.E{Key: k, Value: doc[k]})
+		}
+		return filter
+	}
+}
+
+// appendCondition appends the condition to the filter, or adds it to the top-level $and
+// if the filter already has a condition with the same key.
+func appendCondition(filter bson.D, cond bson.E) bson.D {
+	andIdx := -1
+	collides := false
+	for i, e := range filter {
+		if e.Key == cond.Key {
+			collides = true
+		}
+		if _, ok := e.Value.(bson.A); ok && e.Key == "$and" {
+			andIdx = i
+		}
+	}
+	if !collides {
+		return append(filter, cond)
+	}
+	if andIdx < 0 {
+		return append(filter, bson.E{Key: "$and", Value: bson.A{bson.D{cond}}})
+	}
+	and := filter[andIdx].Value.(bson.A)
+	if cond.Key == "$and" {
+		if conds, ok := cond.Value.(bson.A); ok {
+			filter[andIdx].Value = append(and, conds...)
+			return filter
+		}
+	}
+	filter[andIdx].Value = append(and, bson.D{cond})
+	return filter
+}
@@ -0,0 +1,52 @@
+package mongorepository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitRegexFlags(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		options string
+	}{
+		{pattern: "^abc$", want: "^abc$", options: ""},
+		{pattern: "(?i)^abc$", want: "^abc$", options: "i"},
+		{pattern: "(?ms)^a.c$", want: "^a.c$", options: "ms"},
+		{pattern: "(?iU)a+", want: "(?U)a+", options: "i"},
+		{pattern: "(?i:abc)d", want: "(?i:abc)d", options: ""},
+		{pattern: "(?P<name>abc)", want: "(?P<name>abc)", options: ""},
+		{pattern: "(?i-s)abc", want: "(?i-s)abc", options: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			pattern, options := splitRegexFlags(tt.pattern)
+			assert.Equal(t, tt.want, pattern)
+			assert.Equal(t, tt.options, options)
+		})
+	}
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		want    string
+		bounded bool
+	}{
+		{prefix: "abc", want: "abd", bounded: true},
+		{prefix: "a\uffff", want: "a\U00010000", bounded: true},
+		{prefix: "a\ud7ff", want: "a\ue000", bounded: true},
+		{prefix: "ab\U0010ffff", want: "ac", bounded: true},
+		{prefix: "\U0010ffff", want: "", bounded: false},
+		{prefix: "", want: "", bounded: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			upper, bounded := prefixUpperBound(tt.prefix)
+			assert.Equal(t, tt.want, upper)
+			assert.Equal(t, tt.bounded, bounded)
+		})
+	}
+}
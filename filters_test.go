@@ -0,0 +1,705 @@
+package mongorepository_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFilters(t *testing.T) {
+	type Record struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Title     string             `bson:"title"`
+		CreatedAt time.Time          `bson:"created_at"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Record](db, "records")
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		_, err := repo.Create(context.Background(), Record{
+			Title:     "record",
+			CreatedAt: start.AddDate(0, 0, i),
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("Between", func(t *testing.T) {
+		records, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.Between("created_at", start.AddDate(0, 0, 1), start.AddDate(0, 0, 3)),
+		)
+		require.NoError(t, err)
+		assert.Len(t, records, 3)
+	})
+
+	t.Run("BetweenExclusive", func(t *testing.T) {
+		records, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.BetweenExclusive("created_at", start.AddDate(0, 0, 1), start.AddDate(0, 0, 3)),
+		)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.True(t, start.AddDate(0, 0, 2).Equal(records[0].CreatedAt))
+	})
+
+	t.Run("Not", func(t *testing.T) {
+		_, err := repo.Create(context.Background(), Record{Title: "Draft", CreatedAt: start})
+		require.NoError(t, err)
+
+		records, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.Not(mongorepository.Regex("title", "^dra", "i")),
+		)
+		require.NoError(t, err)
+		assert.Len(t, records, 5)
+
+		records, err = repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.Not(mongorepository.Eq("title", "record")),
+		)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "Draft", records[0].Title)
+	})
+
+	t.Run("AnyEq", func(t *testing.T) {
+		records, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.AnyEq("created_at", start, start.AddDate(0, 0, 4)),
+		)
+		require.NoError(t, err)
+		assert.Len(t, records, 3)
+	})
+}
+
+func TestSameDay(t *testing.T) {
+	type Event struct {
+		ID         primitive.ObjectID `bson:"_id,omitempty"`
+		Name       string             `bson:"name"`
+		OccurredAt time.Time          `bson:"occurred_at"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Event](db, "events")
+
+	// 2024-03-10 in New York (UTC-5 before the DST switch, UTC-4 after it)
+	events := []Event{
+		{Name: "before", OccurredAt: time.Date(2024, 3, 10, 4, 30, 0, 0, time.UTC)},  // 2024-03-09 23:30 local
+		{Name: "morning", OccurredAt: time.Date(2024, 3, 10, 5, 30, 0, 0, time.UTC)}, // 2024-03-10 00:30 local
+		{Name: "evening", OccurredAt: time.Date(2024, 3, 11, 3, 30, 0, 0, time.UTC)}, // 2024-03-10 23:30 local
+		{Name: "after", OccurredAt: time.Date(2024, 3, 11, 4, 30, 0, 0, time.UTC)},   // 2024-03-11 00:30 local
+	}
+	for _, event := range events {
+		_, err := repo.Create(context.Background(), event)
+		require.NoError(t, err)
+	}
+
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	day := time.Date(2024, 3, 10, 12, 0, 0, 0, loc)
+
+	found, err := repo.FindManyByFilter(
+		context.Background(), 0, 0,
+		mongorepository.SameDay("occurred_at", day, "America/New_York"),
+	)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "morning", found[0].Name)
+	assert.Equal(t, "evening", found[1].Name)
+
+	// The same day in UTC matches other events
+	found, err = repo.FindManyByFilter(
+		context.Background(), 0, 0,
+		mongorepository.SameDay("occurred_at", time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), ""),
+	)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "before", found[0].Name)
+	assert.Equal(t, "morning", found[1].Name)
+}
+
+func TestWhere(t *testing.T) {
+	type Product struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Name     string             `bson:"name"`
+		Category string             `bson:"category"`
+		Stock    int                `bson:"stock"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Product](db, "products")
+
+	products := []Product{
+		{Name: "pen", Category: "office", Stock: 10},
+		{Name: "pencil", Category: "office", Stock: 7},
+		{Name: "paper", Category: "office", Stock: 4},
+		{Name: "mug", Category: "kitchen", Stock: 6},
+	}
+	for _, product := range products {
+		_, err := repo.Create(context.Background(), product)
+		require.NoError(t, err)
+	}
+
+	t.Run("WithEq", func(t *testing.T) {
+		// Even stock in the office category
+		found, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.Eq("category", "office"),
+			mongorepository.Where(bson.M{"stock": bson.M{"$mod": bson.A{2, 0}}}),
+		)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, "pen", found[0].Name)
+		assert.Equal(t, "paper", found[1].Name)
+	})
+
+	t.Run("WithOr", func(t *testing.T) {
+		found, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.Or(
+				mongorepository.Eq("category", "kitchen"),
+				mongorepository.Where(bson.M{"$expr": bson.M{"$lt": bson.A{"$stock", 5}}}),
+			),
+		)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, "paper", found[0].Name)
+		assert.Equal(t, "mug", found[1].Name)
+	})
+
+	t.Run("CollidingKeys", func(t *testing.T) {
+		// Both conditions on stock must hold
+		found, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.Gt("stock", 5),
+			mongorepository.Where(bson.M{"stock": bson.M{"$lt": 8}}),
+		)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, "pencil", found[0].Name)
+		assert.Equal(t, "mug", found[1].Name)
+	})
+
+	t.Run("And", func(t *testing.T) {
+		found, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.And(
+				mongorepository.Eq("category", "office"),
+				mongorepository.Gte("stock", 7),
+			),
+		)
+		require.NoError(t, err)
+		assert.Len(t, found, 2)
+	})
+}
+
+func TestNestedExists(t *testing.T) {
+	type Item struct {
+		Name string `bson:"name"`
+		SKU  string `bson:"sku,omitempty"`
+	}
+	type Order struct {
+		Items []Item `bson:"items"`
+	}
+	type Customer struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Name   string             `bson:"name"`
+		Orders []Order            `bson:"orders,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Customer](db, "customers")
+
+	customers := []Customer{
+		// The sku exists in one item but not in the other
+		{Name: "mixed", Orders: []Order{
+			{Items: []Item{{Name: "book"}}},
+			{Items: []Item{{Name: "pen"}, {Name: "mug", SKU: "MUG-1"}}},
+		}},
+		// No item has the sku
+		{Name: "without_sku", Orders: []Order{
+			{Items: []Item{{Name: "book"}}},
+			{Items: []Item{}},
+		}},
+		// No orders at all
+		{Name: "no_orders"},
+	}
+	for _, customer := range customers {
+		_, err := repo.Create(context.Background(), customer)
+		require.NoError(t, err)
+	}
+
+	t.Run("Exists", func(t *testing.T) {
+		found, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.NestedExists("orders.items.sku", true),
+		)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, "mixed", found[0].Name)
+	})
+
+	t.Run("NotExists", func(t *testing.T) {
+		found, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.NestedExists("orders.items.sku", false),
+		)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, "without_sku", found[0].Name)
+		assert.Equal(t, "no_orders", found[1].Name)
+	})
+
+	t.Run("SomeElementMisses", func(t *testing.T) {
+		found, err := repo.FindManyByFilter(
+			context.Background(), 0, 0,
+			mongorepository.Where(bson.M{"orders.items": bson.M{
+				"$elemMatch": bson.M{"sku": bson.M{"$exists": false}},
+			}}),
+		)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, "mixed", found[0].Name)
+		assert.Equal(t, "without_sku", found[1].Name)
+	})
+}
+
+func TestRegexCompiled(t *testing.T) {
+	type Note struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Text string             `bson:"text"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Note](db, "notes")
+
+	for _, text := range []string{"Hello world", "hello there", "first line\nHello again", "goodbye"} {
+		_, err := repo.Create(context.Background(), Note{Text: text})
+		require.NoError(t, err)
+	}
+
+	t.Run("CaseSensitive", func(t *testing.T) {
+		notes, err := repo.FindManyByFilter(context.Background(), 0, 0,
+			mongorepository.RegexCompiled("text", regexp.MustCompile(`^Hello`)))
+		require.NoError(t, err)
+		require.Len(t, notes, 1)
+		assert.Equal(t, "Hello world", notes[0].Text)
+	})
+
+	t.Run("CaseInsensitive", func(t *testing.T) {
+		notes, err := repo.FindManyByFilter(context.Background(), 0, 0,
+			mongorepository.RegexCompiled("text", regexp.MustCompile(`(?i)^hello`)))
+		require.NoError(t, err)
+		assert.Len(t, notes, 2)
+	})
+
+	t.Run("Multiline", func(t *testing.T) {
+		notes, err := repo.FindManyByFilter(context.Background(), 0, 0,
+			mongorepository.RegexCompiled("text", regexp.MustCompile(`(?im)^hello`)))
+		require.NoError(t, err)
+		assert.Len(t, notes, 3)
+	})
+}
+
+func TestStringMatchFilters(t *testing.T) {
+	type File struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[File](db, "files")
+
+	for _, name := range []string{"Report.pdf", "report(1).PDF", "reportXpdf", "summary.txt", "a.b*c"} {
+		_, err := repo.Create(context.Background(), File{Name: name})
+		require.NoError(t, err)
+	}
+	names := func(t *testing.T, filter mongorepository.FilterFunc) []string {
+		files, err := repo.FindManyByFilter(context.Background(), 0, 0, filter)
+		if errors.Is(err, mongorepository.ErrNotFound) {
+			return nil
+		}
+		require.NoError(t, err)
+		result := make([]string, 0, len(files))
+		for _, f := range files {
+			result = append(result, f.Name)
+		}
+		return result
+	}
+
+	t.Run("StartsWith", func(t *testing.T) {
+		assert.Equal(t, []string{"Report.pdf", "report(1).PDF", "reportXpdf"}, names(t, mongorepository.StartsWith("name", "REPORT")))
+		assert.Equal(t, []string{"report(1).PDF"}, names(t, mongorepository.StartsWith("name", "report(1")))
+		// Not anchored anywhere else
+		assert.Empty(t, names(t, mongorepository.StartsWith("name", "pdf")))
+	})
+
+	t.Run("Contains", func(t *testing.T) {
+		// The dot is matched literally, so "reportXpdf" is not matched
+		assert.Equal(t, []string{"Report.pdf", "report(1).PDF"}, names(t, mongorepository.Contains("name", ".pdf")))
+		assert.Equal(t, []string{"a.b*c"}, names(t, mongorepository.Contains("name", "b*")))
+		assert.Empty(t, names(t, mongorepository.Contains("name", ".*")))
+	})
+
+	t.Run("EndsWith", func(t *testing.T) {
+		assert.Equal(t, []string{"Report.pdf", "report(1).PDF"}, names(t, mongorepository.EndsWith("name", ".PDF")))
+		assert.Equal(t, []string{"a.b*c"}, names(t, mongorepository.EndsWith("name", "*c")))
+		assert.Empty(t, names(t, mongorepository.EndsWith("name", "report")))
+	})
+}
+
+func TestSize(t *testing.T) {
+	type Post struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+		Tags []string           `bson:"tags"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Post](db, "posts")
+
+	for _, post := range []Post{
+		{Name: "empty", Tags: []string{}},
+		{Name: "one", Tags: []string{"go"}},
+		{Name: "two", Tags: []string{"go", "mongodb"}},
+		{Name: "three", Tags: []string{"go", "mongodb", "backend"}},
+	} {
+		_, err := repo.Create(context.Background(), post)
+		require.NoError(t, err)
+	}
+
+	for n, name := range []string{"empty", "one", "two", "three"} {
+		posts, err := repo.FindManyByFilter(context.Background(), 0, 0, mongorepository.Size("tags", n))
+		require.NoError(t, err)
+		require.Len(t, posts, 1)
+		assert.Equal(t, name, posts[0].Name)
+	}
+
+	_, err := repo.FindManyByFilter(context.Background(), 0, 0, mongorepository.Size("tags", 4))
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+
+	// Ranges via $expr
+	posts, err := repo.FindManyByFilter(context.Background(), 0, 0,
+		mongorepository.Where(bson.M{"$expr": bson.M{"$gte": bson.A{bson.M{"$size": "$tags"}, 2}}}))
+	require.NoError(t, err)
+	assert.Len(t, posts, 2)
+}
+
+func TestAll(t *testing.T) {
+	type User struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Name   string             `bson:"name"`
+		Active bool               `bson:"active"`
+		Tags   []string           `bson:"tags"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	for _, user := range []User{
+		{Name: "John", Active: true, Tags: []string{"mongodb", "go", "docker"}},
+		{Name: "Jane", Active: false, Tags: []string{"go", "mongodb"}},
+		{Name: "Alex", Active: true, Tags: []string{"go"}},
+		{Name: "Kate", Active: true, Tags: []string{"mongodb", "python"}},
+	} {
+		_, err := repo.Create(context.Background(), user)
+		require.NoError(t, err)
+	}
+
+	users, err := repo.FindManyByFilter(context.Background(), 0, 0,
+		mongorepository.All("tags", []string{"go", "mongodb"}))
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "John", users[0].Name)
+	assert.Equal(t, "Jane", users[1].Name)
+
+	// Composes with And
+	users, err = repo.FindManyByFilter(context.Background(), 0, 0, mongorepository.And(
+		mongorepository.All("tags", []string{"go", "mongodb"}),
+		mongorepository.Eq("active", true),
+	))
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "John", users[0].Name)
+}
+
+func TestExpr(t *testing.T) {
+	type Project struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Name   string             `bson:"name"`
+		Budget float64            `bson:"budget"`
+		Spent  float64            `bson:"spent"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Project](db, "projects")
+
+	for _, project := range []Project{
+		{Name: "under", Budget: 1000, Spent: 500},
+		{Name: "exact", Budget: 1000, Spent: 1000},
+		{Name: "over", Budget: 1000, Spent: 1500},
+	} {
+		_, err := repo.Create(context.Background(), project)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name   string
+		filter mongorepository.FilterFunc
+		want   []string
+	}{
+		{"Gt", mongorepository.ExprGt("spent", "budget"), []string{"over"}},
+		{"Gte", mongorepository.ExprGte("spent", "budget"), []string{"exact", "over"}},
+		{"Lt", mongorepository.ExprLt("spent", "budget"), []string{"under"}},
+		{"Lte", mongorepository.ExprLte("spent", "budget"), []string{"under", "exact"}},
+		{"Eq", mongorepository.ExprEq("spent", "budget"), []string{"exact"}},
+		{"Ne", mongorepository.ExprNe("spent", "budget"), []string{"under", "over"}},
+		{"Expr", mongorepository.Expr(bson.M{"$gt": bson.A{"$spent", bson.M{"$multiply": bson.A{"$budget", 0.9}}}}), []string{"exact", "over"}},
+		// Both $expr conditions must hold
+		{"Combined", func(filter bson.D) bson.D {
+			filter = mongorepository.ExprGte("spent", "budget")(filter)
+			return mongorepository.ExprLte("spent", "budget")(filter)
+		}, []string{"exact"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects, err := repo.FindManyByFilter(context.Background(), 0, 0, tt.filter)
+			require.NoError(t, err)
+			names := make([]string, len(projects))
+			for i, p := range projects {
+				names[i] = p.Name
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestNotEmpty(t *testing.T) {
+	type Post struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+		Tags []string           `bson:"tags,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Post](db, "posts")
+
+	for _, post := range []Post{
+		{Name: "missing"},
+		{Name: "populated", Tags: []string{"go", "mongodb"}},
+	} {
+		_, err := repo.Create(context.Background(), post)
+		require.NoError(t, err)
+	}
+	// Empty and null arrays are stored as is, bypassing omitempty
+	_, err := db.Collection("posts").InsertMany(context.Background(), []interface{}{
+		bson.M{"name": "empty", "tags": bson.A{}},
+		bson.M{"name": "null", "tags": nil},
+	})
+	require.NoError(t, err)
+
+	posts, err := repo.FindManyByFilter(context.Background(), 0, 0, mongorepository.NotEmpty("tags"))
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	assert.Equal(t, "populated", posts[0].Name)
+
+	// Negated, it matches the empty, null and missing arrays
+	posts, err = repo.FindManyByFilter(context.Background(), 0, 0, mongorepository.Not(mongorepository.NotEmpty("tags")))
+	require.NoError(t, err)
+	assert.Len(t, posts, 3)
+}
+
+func TestIsNullIsMissing(t *testing.T) {
+	type User struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Name     string             `bson:"name"`
+		Nickname *string            `bson:"nickname"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	nickname := "Johnny"
+	_, err := repo.Create(context.Background(), User{Name: "set", Nickname: &nickname})
+	require.NoError(t, err)
+	// Stored with explicit null
+	_, err = repo.Create(context.Background(), User{Name: "null"})
+	require.NoError(t, err)
+	_, err = db.Collection("users").InsertOne(context.Background(), bson.M{"name": "missing"})
+	require.NoError(t, err)
+
+	names := func(filter mongorepository.FilterFunc) []string {
+		users, err := repo.FindManyByFilter(context.Background(), 0, 0, filter)
+		require.NoError(t, err)
+		result := make([]string, 0, len(users))
+		for _, u := range users {
+			result = append(result, u.Name)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"null", "missing"}, names(mongorepository.IsNull("nickname")))
+	assert.Equal(t, []string{"missing"}, names(mongorepository.IsMissing("nickname")))
+	assert.Equal(t, []string{"null"}, names(mongorepository.Where(bson.M{"nickname": bson.M{"$type": "null"}})))
+	assert.Equal(t, []string{"set"}, names(mongorepository.Not(mongorepository.IsNull("nickname"))))
+}
+
+func TestEqDoc(t *testing.T) {
+	type Address struct {
+		City string `bson:"city"`
+		Zip  string `bson:"zip"`
+	}
+	type User struct {
+		ID      primitive.ObjectID `bson:"_id,omitempty"`
+		Name    string             `bson:"name"`
+		Address Address            `bson:"address"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	_, err := repo.Create(context.Background(), User{Name: "john", Address: Address{City: "Berlin", Zip: "10115"}})
+	require.NoError(t, err)
+	_, err = repo.Create(context.Background(), User{Name: "jane", Address: Address{City: "Berlin", Zip: "10117"}})
+	require.NoError(t, err)
+	// The same fields stored in another order
+	_, err = db.Collection("users").InsertOne(context.Background(), bson.D{
+		{Key: "name", Value: "alex"},
+		{Key: "address", Value: bson.D{{Key: "zip", Value: "10115"}, {Key: "city", Value: "Berlin"}}},
+	})
+	require.NoError(t, err)
+
+	names := func(filter mongorepository.FilterFunc) []string {
+		users, err := repo.FindManyByFilter(context.Background(), 0, 0, filter)
+		if errors.Is(err, mongorepository.ErrNotFound) {
+			return nil
+		}
+		require.NoError(t, err)
+		result := make([]string, 0, len(users))
+		for _, u := range users {
+			result = append(result, u.Name)
+		}
+		return result
+	}
+
+	t.Run("ExactMatch", func(t *testing.T) {
+		assert.Equal(t, []string{"john"}, names(mongorepository.EqDoc("address", Address{City: "Berlin", Zip: "10115"})))
+		assert.Equal(t, []string{"alex"}, names(mongorepository.EqDoc("address", bson.D{
+			{Key: "zip", Value: "10115"}, {Key: "city", Value: "Berlin"},
+		})))
+		// A subset of the fields doesn't match the whole embedded document
+		assert.Empty(t, names(mongorepository.EqDoc("address", bson.D{{Key: "city", Value: "Berlin"}})))
+	})
+
+	t.Run("PartialMatch", func(t *testing.T) {
+		assert.Equal(t, []string{"john", "jane", "alex"}, names(mongorepository.Eq("address.city", "Berlin")))
+	})
+}
+
+func TestOnDate(t *testing.T) {
+	type Event struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Name      string             `bson:"name"`
+		CreatedAt time.Time          `bson:"created_at"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Event](db, "events")
+
+	// 2024-05-10 in Tokyo (UTC+9) spans 2024-05-09 15:00 to 2024-05-10 15:00 UTC
+	events := []Event{
+		{Name: "before", CreatedAt: time.Date(2024, 5, 9, 14, 59, 0, 0, time.UTC)}, // 2024-05-09 23:59 local
+		{Name: "start", CreatedAt: time.Date(2024, 5, 9, 15, 0, 0, 0, time.UTC)},   // 2024-05-10 00:00 local
+		{Name: "end", CreatedAt: time.Date(2024, 5, 10, 14, 59, 0, 0, time.UTC)},   // 2024-05-10 23:59 local
+		{Name: "after", CreatedAt: time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC)},  // 2024-05-11 00:00 local
+	}
+	for _, event := range events {
+		_, err := repo.Create(context.Background(), event)
+		require.NoError(t, err)
+	}
+
+	names := func(filter mongorepository.FilterFunc) []string {
+		found, err := repo.FindManyByFilter(context.Background(), 0, 0, filter)
+		require.NoError(t, err)
+		result := make([]string, 0, len(found))
+		for _, e := range found {
+			result = append(result, e.Name)
+		}
+		return result
+	}
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	t.Run("Timezone", func(t *testing.T) {
+		day := time.Date(2024, 5, 10, 12, 0, 0, 0, tokyo)
+		assert.Equal(t, []string{"start", "end"}, names(mongorepository.OnDate("created_at", day, tokyo)))
+	})
+
+	t.Run("DayConvertedToTimezone", func(t *testing.T) {
+		// 2024-05-09 20:00 UTC is already 2024-05-10 in Tokyo
+		day := time.Date(2024, 5, 9, 20, 0, 0, 0, time.UTC)
+		assert.Equal(t, []string{"start", "end"}, names(mongorepository.OnDate("created_at", day, tokyo)))
+	})
+
+	t.Run("UTC", func(t *testing.T) {
+		day := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, []string{"end", "after"}, names(mongorepository.OnDate("created_at", day, nil)))
+	})
+}
+
+func TestPrefixIndexed(t *testing.T) {
+	type Product struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Product](db, "products")
+	require.NoError(t, repo.CreateIndex(context.Background(), "name"))
+
+	for _, name := range []string{"apple", "apricot", "app🍏", "application", "banana", "Apple", "cherry", "grape"} {
+		_, err := repo.Create(context.Background(), Product{Name: name})
+		require.NoError(t, err)
+	}
+
+	t.Run("Matches", func(t *testing.T) {
+		sorted := mongorepository.WithQueryOptions(context.Background(), mongorepository.WithSort(bson.D{{Key: "name", Value: 1}}))
+		products, err := repo.FindManyByFilter(sorted, 0, 0, mongorepository.PrefixIndexed("name", "app"))
+		require.NoError(t, err)
+		names := make([]string, 0, len(products))
+		for _, p := range products {
+			names = append(names, p.Name)
+		}
+		// Case-sensitive, including the characters beyond U+FFFF
+		assert.Equal(t, []string{"apple", "application", "app🍏"}, names)
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		keysExamined := func(filter mongorepository.FilterFunc) int64 {
+			plan, err := repo.Explain(context.Background(), filter)
+			require.NoError(t, err)
+			planner, ok := plan["queryPlanner"].(bson.M)
+			require.True(t, ok)
+			assert.Contains(t, fmtPlan(planner["winningPlan"]), "IXSCAN")
+			stats, ok := plan["executionStats"].(bson.M)
+			require.True(t, ok)
+			return toInt64(t, stats["totalKeysExamined"])
+		}
+
+		rangeKeys := keysExamined(mongorepository.PrefixIndexed("name", "app"))
+		regexKeys := keysExamined(mongorepository.StartsWith("name", "app"))
+		// The range only examines the keys having the prefix, while the case-insensitive regex can't narrow the scan
+		assert.LessOrEqual(t, rangeKeys, regexKeys)
+	})
+}
@@ -0,0 +1,37 @@
+package mongorepository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindConfig wraps the MongoDB FindOptions to tune the cursor of FindManyByFilterWithConfig,
+// e.g. for large result sets.
+type FindConfig func(*options.FindOptions)
+
+// BatchSize sets the number of documents the server returns per batch of the cursor,
+// e.g. to lower the memory usage of large result sets or the number of round trips.
+func BatchSize(n int32) FindConfig {
+	return func(opts *options.FindOptions) {
+		opts.SetBatchSize(n)
+	}
+}
+
+// MaxTime sets the server-side time limit of the query, after which the server aborts it
+// with a MaxTimeMSExpired error, even if the client is still waiting. Unlike a context deadline,
+// it also frees the server resources of the aborted query.
+func MaxTime(d time.Duration) FindConfig {
+	return func(opts *options.FindOptions) {
+		opts.SetMaxTime(d)
+	}
+}
+
+// NoCursorTimeout prevents the server from closing the cursor after its default 10 minutes
+// of inactivity, e.g. for slow consumers of large result sets. Note that such cursors are still closed
+// when their session expires.
+func NoCursorTimeout(noTimeout bool) FindConfig {
+	return func(opts *options.FindOptions) {
+		opts.SetNoCursorTimeout(noTimeout)
+	}
+}
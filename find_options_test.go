@@ -0,0 +1,49 @@
+package mongorepository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestFindManyByFilterWithConfig(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	for i := 0; i < 20; i++ {
+		_, err := repo.Create(context.Background(), User{Name: fmt.Sprintf("user%d", i)})
+		require.NoError(t, err)
+	}
+
+	t.Run("BatchSize", func(t *testing.T) {
+		config := []mongorepository.FindConfig{mongorepository.BatchSize(3), mongorepository.NoCursorTimeout(true)}
+		// All the batches are fetched
+		users, err := repo.FindManyByFilterWithConfig(context.Background(), 0, 20, config)
+		require.NoError(t, err)
+		assert.Len(t, users, 20)
+	})
+
+	t.Run("MaxTime", func(t *testing.T) {
+		config := []mongorepository.FindConfig{mongorepository.MaxTime(10 * time.Millisecond)}
+		// Each document takes 50ms to match
+		_, err := repo.FindManyByFilterWithConfig(context.Background(), 0, 20, config, mongorepository.Where(bson.M{"$where": "sleep(50) || true"}))
+		require.ErrorIs(t, err, mongorepository.ErrFailedToFindManyByFilter)
+
+		var serverErr mongo.ServerError
+		require.True(t, errors.As(err, &serverErr))
+		assert.True(t, serverErr.HasErrorCode(50), "expected MaxTimeMSExpired, got %v", err)
+	})
+}
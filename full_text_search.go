@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"unicode"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,25 +15,61 @@ import (
 // CreateFullTextIndex creates a full-text index in the MongoDB collection based on the specified key and options.
 // It takes a context.Context as the first argument, the key for the index as the second argument,
 // and optional IndexOption(s) as the third argument(s).
+// If the collection already has a different text index, e.g. with other keys or weights,
+// it returns an error with the ErrTextIndexExists error code, so it can be ignored on startup;
+// creating the same text index again is a no-op.
 // The function returns an error if the index creation fails.
-func (r *mongoRepository[T]) CreateFullTextIndex(ctx context.Context, keys map[string]int32, lang string) error {
+func (r *mongoRepository[T]) CreateFullTextIndex(ctx context.Context, keys map[string]int32, lang string) (err error) {
+	ctx, op := r.startOperation(ctx, "CreateFullTextIndex")
+	defer func() { op.end(err) }()
+
+	return r.createTextIndex(ctx, TextIndexConfig{Fields: keys, DefaultLang: lang})
+}
+
+// CreateTextIndex creates a full-text index in the MongoDB collection based on the config,
+// which, unlike CreateFullTextIndex, allows to customize the index name and the field holding
+// the per-document language. The index name defaults to "<DefaultLang>_fts_index" and the language to English.
+// MongoDB allows only one text index per collection, so creating a text index when the collection
+// already has a different one fails with an error with the ErrTextIndexExists error code, naming the existing index.
+// Creating the same text index again is a no-op.
+// The function returns an error if the index creation fails.
+func (r *mongoRepository[T]) CreateTextIndex(ctx context.Context, cfg TextIndexConfig) (err error) {
+	ctx, op := r.startOperation(ctx, "CreateTextIndex")
+	defer func() { op.end(err) }()
+
+	return r.createTextIndex(ctx, cfg)
+}
+
+// createTextIndex creates a full-text index based on the config.
+func (r *mongoRepository[T]) createTextIndex(ctx context.Context, cfg TextIndexConfig) error {
 	// Build the index keys and weights
-	idxKeys := make(bson.D, 0, len(keys))
-	weights := make(bson.D, 0, len(keys))
-	for k, w := range keys {
+	idxKeys := make(bson.D, 0, len(cfg.Fields))
+	weights := make(bson.D, 0, len(cfg.Fields))
+	for k, w := range cfg.Fields {
 		idxKeys = append(idxKeys, bson.E{Key: k, Value: "text"})
 		weights = append(weights, bson.E{Key: k, Value: w})
 	}
+	if len(idxKeys) == 0 {
+		return errors.Join(ErrFailedToCreateIndex, ErrNoIndexKeys)
+	}
+	lang := cfg.DefaultLang
 	if lang == "" {
 		lang = "english"
 	}
+	name := cfg.Name
+	if name == "" {
+		name = fmt.Sprintf("%s_fts_index", lang)
+	}
 
 	// Set index options
 	idxOpt := options.Index()
 	idxOpt.SetWeights(weights)
 	idxOpt.SetDefaultLanguage(lang)
-	idxOpt.SetName(fmt.Sprintf("%s_fts_index", lang))
+	idxOpt.SetName(name)
 	idxOpt.SetSparse(true)
+	if cfg.LanguageOverride != "" {
+		idxOpt.SetLanguageOverride(cfg.LanguageOverride)
+	}
 
 	// Create the index
 	indexModel := mongo.IndexModel{
@@ -41,50 +79,414 @@ func (r *mongoRepository[T]) CreateFullTextIndex(ctx context.Context, keys map[s
 
 	// Create the index
 	if _, err := r.collection.Indexes().CreateOne(ctx, indexModel); err != nil {
+		if isIndexConflictError(err) {
+			if existing, ok := r.existingTextIndex(ctx); ok {
+				return errors.Join(ErrFailedToCreateIndex, ErrTextIndexExists, fmt.Errorf(
+					"collection already has text index %q, only one text index is allowed per collection", existing,
+				), err)
+			}
+		}
 		return errors.Join(ErrFailedToCreateIndex, err)
 	}
 	return nil
 }
 
+// existingTextIndex returns the name of the text index of the collection, if any.
+func (r *mongoRepository[T]) existingTextIndex(ctx context.Context) (string, bool) {
+	specs, err := r.listIndexSpecs(ctx)
+	if err != nil {
+		return "", false
+	}
+	for _, spec := range specs {
+		if isTextIndexSpec(spec) {
+			return spec.Name, true
+		}
+	}
+	return "", false
+}
+
+// UpdateTextIndexWeights makes the text index of the collection match the fields with the given weights
+// and default language, e.g. on every application start after changing the weights.
+// If the existing text index already matches them, it is a no-op, so the index isn't rebuilt needlessly
+// on large collections. Otherwise, the existing text index is dropped and the new one is created;
+// the documents are kept, but searches fail with the ErrNoTextIndex error until the new index is created.
+// The recreated index keeps the language override field and the custom name of the existing one,
+// e.g. set with CreateTextIndex; a default name is updated to match the new language.
+// If the collection has no text index, it is created.
+// The function returns an error if the index can't be dropped or created.
+func (r *mongoRepository[T]) UpdateTextIndexWeights(ctx context.Context, keys map[string]int32, lang string) (err error) {
+	ctx, op := r.startOperation(ctx, "UpdateTextIndexWeights")
+	defer func() { op.end(err) }()
+
+	if lang == "" {
+		lang = "english"
+	}
+	specs, err := r.listIndexSpecs(ctx)
+	if err != nil {
+		return errors.Join(ErrFailedToCreateIndex, err)
+	}
+	cfg := TextIndexConfig{Fields: keys, DefaultLang: lang}
+	for _, spec := range specs {
+		if !isTextIndexSpec(spec) {
+			continue
+		}
+		if spec.DefaultLanguage == lang && sameWeights(spec.Weights, keys) {
+			return nil
+		}
+		if spec.Name != fmt.Sprintf("%s_fts_index", spec.DefaultLanguage) {
+			cfg.Name = spec.Name
+		}
+		cfg.LanguageOverride = spec.LanguageOverride
+		if _, err := r.collection.Indexes().DropOne(ctx, spec.Name); err != nil {
+			return errors.Join(ErrFailedToCreateIndex, ErrFailedToDropIndex, err)
+		}
+	}
+	return r.createTextIndex(ctx, cfg)
+}
+
+// isTextIndexSpec reports whether the index is a text index.
+func isTextIndexSpec(spec indexSpec) bool {
+	for _, key := range spec.Key {
+		if key.Key == "_fts" && key.Value == "text" {
+			return true
+		}
+	}
+	return false
+}
+
+// sameWeights reports whether the weights of the existing text index equal the requested ones.
+func sameWeights(existing, requested map[string]int32) bool {
+	if len(existing) != len(requested) {
+		return false
+	}
+	for field, weight := range requested {
+		if w, ok := existing[field]; !ok || w != weight {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchableRepository is a Repository with the full-text search methods,
+// so that the code using them can be mocked against an interface.
+type SearchableRepository[T any] interface {
+	Repository[T]
+
+	// CreateFullTextIndex creates a full-text index on the fields with the given weights and default language.
+	// The function returns an error if the index creation fails.
+	CreateFullTextIndex(ctx context.Context, keys map[string]int32, lang string) error
+
+	// CreateTextIndex creates a full-text index in the MongoDB collection based on the config.
+	// The function returns an error if the index creation fails.
+	CreateTextIndex(ctx context.Context, cfg TextIndexConfig) error
+
+	// UpdateTextIndexWeights makes the text index match the fields with the given weights and default language,
+	// recreating it only if they changed.
+	// The function returns an error if the index can't be dropped or created.
+	UpdateTextIndexWeights(ctx context.Context, keys map[string]int32, lang string) error
+
+	// Search finds documents in the collection based on the provided search term, ordered by relevance.
+	// The function returns a slice of documents of type T and an error.
+	Search(ctx context.Context, skip, limit int64, searchTerm string, opts ...SearchOptions) ([]T, error)
+
+	// SearchWithScores finds documents in the collection based on the provided search term, the same way as Search,
+	// and returns them along with their text score.
+	// The function returns a slice of ScoredResult and an error.
+	SearchWithScores(ctx context.Context, skip, limit int64, searchTerm string, opts ...SearchOptions) ([]ScoredResult[T], error)
+
+	// SearchIterate streams the documents matching the provided search term and filters through the callback,
+	// one by one in relevance order, along with their text score.
+	// The function returns an error if the search fails.
+	SearchIterate(ctx context.Context, searchTerm string, fn func(T, float64) error, filters ...FilterFunc) error
+
+	// SearchPipeline runs a custom search aggregation pipeline, e.g. an Atlas Search $search stage,
+	// and decodes the resulting documents.
+	// The function returns a slice of documents of type T and an error.
+	SearchPipeline(ctx context.Context, pipeline mongo.Pipeline) ([]T, error)
+}
+
+// SearchOptions configures a full-text search.
+type SearchOptions struct {
+	Language           string // Language for stemming and stop words, the default language of the text index if empty
+	CaseSensitive      bool   // Whether to match the case of the search term, e.g. for exact phrase searches
+	DiacriticSensitive bool   // Whether to match the diacritical marks, e.g. "café" doesn't match "cafe"
+	Sort               bson.D // Tiebreaker applied after the relevance, e.g. {created_at: -1}, to order the documents with equal scores deterministically
+}
+
+// textQuery builds the $text query operator for the search term.
+func (o SearchOptions) textQuery(searchTerm string) bson.M {
+	query := bson.M{"$search": searchTerm}
+	if o.Language != "" {
+		query["$language"] = o.Language
+	}
+	if o.CaseSensitive {
+		query["$caseSensitive"] = true
+	}
+	if o.DiacriticSensitive {
+		query["$diacriticSensitive"] = true
+	}
+	return query
+}
+
+// SanitizeSearchTerm cleans up a user-supplied search term before passing it to Search,
+// so stray quotes and dashes don't change the meaning of the query unexpectedly.
+// It preserves the well-formed $text search syntax:
+//   - "exact phrase": a phrase in balanced double quotes matches documents containing the exact phrase;
+//   - -word and -"exact phrase": a dash at the start of a word or right before a phrase excludes
+//     the documents containing them.
+//
+// An unbalanced quote is removed, so the rest is searched as plain words, lone and repeated dashes
+// are dropped or collapsed, trailing dashes are trimmed, and backslashes, which escape quotes in $text,
+// are removed. Whitespace is normalized.
+// Note that a term with only excluded words or phrases still matches nothing.
+func SanitizeSearchTerm(s string) string {
+	s = strings.ReplaceAll(s, `\`, "")
+	if strings.Count(s, `"`)%2 != 0 {
+		i := strings.LastIndex(s, `"`)
+		s = s[:i] + " " + s[i+1:]
+	}
+
+	// Odd segments are the phrases inside balanced quotes
+	segments := strings.Split(s, `"`)
+	tokens := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		if i%2 == 1 {
+			words := strings.Fields(segment)
+			if len(words) == 0 {
+				continue
+			}
+			phrase := `"` + strings.Join(words, " ") + `"`
+			// A dash at the start of a word right before the opening quote negates the phrase
+			prev := segments[i-1]
+			if dashed := strings.TrimRight(prev, "-"); dashed != prev &&
+				(dashed == "" || strings.TrimRightFunc(dashed, unicode.IsSpace) != dashed) {
+				phrase = "-" + phrase
+			}
+			tokens = append(tokens, phrase)
+			continue
+		}
+		for _, word := range strings.Fields(segment) {
+			negated := strings.HasPrefix(word, "-")
+			word = strings.Trim(word, "-")
+			if word == "" {
+				continue
+			}
+			if negated {
+				word = "-" + word
+			}
+			tokens = append(tokens, word)
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
 // Search finds documents in the collection based on the provided search term.
 // It allows skipping a certain number of documents and limiting the number of documents to be returned.
+// If limit is zero, the default limit is applied, like in FindManyByFilter, and NoLimit returns all matching documents.
+// An optional SearchOptions configures the language and the case and diacritic sensitivity of the search,
+// and the tiebreaker sort for the documents with the same relevance. Passing more than one SearchOptions
+// returns an error with the ErrTooManySearchOptions error code.
+// If the collection has no text index, it returns an error with the ErrNoTextIndex error code.
+// If no documents match, it returns an error with the ErrNotFound error code,
+// or an empty slice if the repository is created with WithEmptyResultNil.
 // The function returns a slice of documents of type T and an error.
-func (r *mongoRepository[T]) Search(ctx context.Context, skip, limit int64, searchTerm string) ([]T, error) {
-	filter := bson.M{"$text": bson.M{"$search": searchTerm}}
-	if limit == 0 {
-		limit = 10
+func (r *mongoRepository[T]) Search(ctx context.Context, skip, limit int64, searchTerm string, opts ...SearchOptions) (_ []T, err error) {
+	ctx, op := r.startOperation(ctx, "Search")
+	defer func() { op.end(err) }()
+
+	cursor, err := r.findSearch(ctx, skip, limit, searchTerm, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []T
+	for cursor.Next(ctx) {
+		var element T
+		if err := cursor.Decode(&element); err != nil {
+			return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+		results = append(results, element)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, searchError(err)
+	}
+	if len(results) == 0 {
+		if r.opts.emptyResultNil{
+			return []T{}, nil
+		}
+		return nil, errors.Join(ErrFailedToFindManyByFilter, ErrNotFound)
+	}
+
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// ScoredResult holds a document found by a full-text search and its relevance score.
+type ScoredResult[T any] struct {
+	Doc   T       // Found document
+	Score float64 // Text score of the document, the higher the more relevant
+}
+
+// SearchWithScores finds documents in the collection based on the provided search term, the same way as Search,
+// and returns them along with their text score, e.g. to display the relevance.
+// If the collection has no text index, it returns an error with the ErrNoTextIndex error code.
+// The function returns a slice of ScoredResult and an error.
+func (r *mongoRepository[T]) SearchWithScores(ctx context.Context, skip, limit int64, searchTerm string, opts ...SearchOptions) (_ []ScoredResult[T], err error) {
+	ctx, op := r.startOperation(ctx, "SearchWithScores")
+	defer func() { op.end(err) }()
+
+	cursor, err := r.findSearch(ctx, skip, limit, searchTerm, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []ScoredResult[T]
+	for cursor.Next(ctx) {
+		var element T
+		if err := cursor.Decode(&element); err != nil {
+			return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+		// The score is injected by the projection, so it's always a double
+		score, _ := cursor.Current.Lookup("score").DoubleOK()
+		results = append(results, ScoredResult[T]{Doc: element, Score: score})
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, searchError(err)
+	}
+	if len(results) == 0 {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, ErrNotFound)
+	}
+
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// findSearch runs the full-text search query sorted by relevance, with the text score
+// injected into the score field of the documents.
+func (r *mongoRepository[T]) findSearch(ctx context.Context, skip, limit int64, searchTerm string, opts ...SearchOptions) (*mongo.Cursor, error) {
+	var searchOpts SearchOptions
+	switch len(opts) {
+	case 0:
+	case 1:
+		searchOpts = opts[0]
+	default:
+		return nil, errors.Join(ErrFailedToFindManyByFilter, ErrTooManySearchOptions)
+	}
+	filter := bson.M{"$text": searchOpts.textQuery(searchTerm)}
+	limit, err := r.opts.limit(limit)
+	if err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
 	}
 	// Set the find options
 	findOptions := options.Find().
 		SetSkip(skip).
 		SetLimit(limit).
 		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
-		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}})
+		SetSort(append(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}, searchOpts.Sort...))
 	// Find documents
-	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Find(ctx, filter, findOptions)
+	})
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.Join(ErrFailedToFindManyByFilter, ErrNotFound, err)
 		}
-		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+		return nil, searchError(err)
+	}
+	return cursor, nil
+}
+
+// SearchIterate streams the documents matching the provided search term and filters through the callback,
+// one by one in relevance order, along with their text score, without buffering the whole result set.
+// If the callback returns an error, the iteration stops and the error is returned.
+// The function returns an error if the search fails.
+func (r *mongoRepository[T]) SearchIterate(ctx context.Context, searchTerm string, fn func(T, float64) error, filters ...FilterFunc) (err error) {
+	ctx, op := r.startStreamingOperation(ctx, "SearchIterate")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{{Key: "$text", Value: bson.M{"$search": searchTerm}}}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	// Set the find options
+	findOptions := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}})
+	// Find documents
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Find(ctx, filter, findOptions)
+	})
+	if err != nil {
+		return searchError(err)
 	}
 	defer cursor.Close(ctx)
 
-	var results []T
+	var count int64
+	defer func() { op.setDocumentCount(count) }()
 	for cursor.Next(ctx) {
 		var element T
 		if err := cursor.Decode(&element); err != nil {
-			return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+			return errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+		// The score is injected by the projection, so it's always a double
+		score, _ := cursor.Current.Lookup("score").DoubleOK()
+		count++
+		if err := fn(element, score); err != nil {
+			return err
 		}
-		results = append(results, element)
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+		return searchError(err)
+	}
+	return nil
+}
+
+// SearchPipeline runs a custom search aggregation pipeline and decodes the resulting documents,
+// for relevance tuning beyond the basic $text search, e.g. an Atlas Search $search stage
+// or a $text match followed by stages computing a custom score.
+// The pipeline is run as is, so it's up to the caller to sort and limit the results.
+// Fields added by the pipeline, e.g. the score, are decoded only if T has matching fields.
+// If the pipeline runs a $text match and the collection has no text index,
+// it returns an error with the ErrNoTextIndex error code.
+// If no documents are found, it returns an error with the ErrNotFound error code.
+// The function returns a slice of documents of type T and an error.
+func (r *mongoRepository[T]) SearchPipeline(ctx context.Context, pipeline mongo.Pipeline) (_ []T, err error) {
+	ctx, op := r.startOperation(ctx, "SearchPipeline")
+	defer func() { op.end(err) }()
+
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Aggregate(ctx, pipeline)
+	})
+	if err != nil {
+		return nil, searchError(err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []T
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, searchError(err)
 	}
 	if len(results) == 0 {
 		return nil, errors.Join(ErrFailedToFindManyByFilter, ErrNotFound)
 	}
 
+	op.setDocumentCount(int64(len(results)))
 	return results, nil
 }
+
+// indexNotFoundCode is the error code returned by MongoDB when a $text query is run without a text index.
+const indexNotFoundCode = 27
+
+// searchError wraps the error of a full-text search, telling apart the missing text index.
+func searchError(err error) error {
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(indexNotFoundCode) {
+		return errors.Join(ErrFailedToFindManyByFilter, ErrNoTextIndex, err)
+	}
+	return errors.Join(ErrFailedToFindManyByFilter, err)
+}
@@ -2,12 +2,17 @@ package mongorepository_test
 
 import (
 	"context"
+	"errors"
+	"math"
 	"testing"
+	"time"
 
 	mongorepository "github.com/dmitrymomot/mongo-repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func TestFullTextSearch(t *testing.T) {
@@ -19,7 +24,7 @@ func TestFullTextSearch(t *testing.T) {
 	}
 
 	db := setupMongoDB(t)
-	repo := mongorepository.NewMongoRepository[User](db, "users")
+	var repo mongorepository.SearchableRepository[User] = mongorepository.NewMongoRepository[User](db, "users")
 
 	// Create unique index for email field
 	require.NoError(t, repo.CreateFullTextIndex(
@@ -122,4 +127,428 @@ func TestFullTextSearch(t *testing.T) {
 		assert.Equal(t, "David Lee", users[0].Name)
 		assert.Equal(t, "Kayla TestJohnson", users[1].Name)
 	})
+
+	// Test full text search with scores
+	t.Run("SearchWithScores", func(t *testing.T) {
+		results, err := repo.SearchWithScores(context.Background(), 0, 10, "test")
+		require.NoError(t, err)
+		require.Len(t, results, 5)
+		assert.Equal(t, "Test John Doe", results[0].Doc.Name)
+		for i, result := range results {
+			assert.Positive(t, result.Score)
+			if i > 0 {
+				assert.LessOrEqual(t, result.Score, results[i-1].Score)
+			}
+		}
+
+		_, err = repo.SearchWithScores(context.Background(), 0, 10, "nonexistentterm")
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+	})
+
+	// Test streaming full text search
+	t.Run("SearchIterate", func(t *testing.T) {
+		var names []string
+		lastScore := math.MaxFloat64
+		err := repo.SearchIterate(context.Background(), "test", func(user User, score float64) error {
+			assert.Positive(t, score)
+			assert.LessOrEqual(t, score, lastScore)
+			lastScore = score
+			names = append(names, user.Name)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Test John Doe", "Alex Brown", "Emily Davis", "Michael Wilson", "Ryan Clark"}, names)
+
+		// Returning an error stops the iteration
+		errStop := errors.New("stop")
+		calls := 0
+		err = repo.SearchIterate(context.Background(), "test", func(User, float64) error {
+			calls++
+			return errStop
+		})
+		require.ErrorIs(t, err, errStop)
+		assert.Equal(t, 1, calls)
+
+		// Additional filters narrow the result set
+		calls = 0
+		err = repo.SearchIterate(context.Background(), "test", func(User, float64) error {
+			calls++
+			return nil
+		}, mongorepository.Eq("name", "Ryan Clark"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestSearchWithoutTextIndex(t *testing.T) {
+	type Note struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Text string             `bson:"text"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Note](db, "notes")
+
+	_, err := repo.Create(context.Background(), Note{Text: "hello world"})
+	require.NoError(t, err)
+
+	_, err = repo.Search(context.Background(), 0, 10, "hello")
+	require.ErrorIs(t, err, mongorepository.ErrNoTextIndex)
+
+	err = repo.SearchIterate(context.Background(), "hello", func(Note, float64) error { return nil })
+	require.ErrorIs(t, err, mongorepository.ErrNoTextIndex)
+}
+
+func TestCreateTextIndex(t *testing.T) {
+	type Article struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Title    string             `bson:"title"`
+		Body     string             `bson:"body"`
+		Language string             `bson:"lang,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	articles := mongorepository.NewMongoRepository[Article](db, "articles")
+	posts := mongorepository.NewMongoRepository[Article](db, "posts")
+
+	findIndex := func(t *testing.T, name string) bool {
+		indexes, err := articles.ListIndexes(context.Background())
+		require.NoError(t, err)
+		for _, idx := range indexes {
+			if idx.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Custom name and language override field
+	require.NoError(t, articles.CreateTextIndex(context.Background(), mongorepository.TextIndexConfig{
+		Name:             "articles_search",
+		Fields:           map[string]int32{"title": 10, "body": 1},
+		LanguageOverride: "lang",
+	}))
+	assert.True(t, findIndex(t, "articles_search"))
+
+	// Other collections can have their own text indexes with other names
+	require.NoError(t, posts.CreateTextIndex(context.Background(), mongorepository.TextIndexConfig{
+		Name:   "posts_search",
+		Fields: map[string]int32{"title": 1},
+	}))
+
+	// The per-document language is taken from the override field, so unsupported languages are rejected
+	_, err := articles.Create(context.Background(), Article{Title: "corriendo", Language: "spanish"})
+	require.NoError(t, err)
+	_, err = articles.Create(context.Background(), Article{Title: "qapla", Language: "klingon"})
+	require.Error(t, err)
+
+	// Only one text index is allowed per collection
+	err = articles.CreateTextIndex(context.Background(), mongorepository.TextIndexConfig{
+		Name:   "articles_body_search",
+		Fields: map[string]int32{"body": 1},
+	})
+	require.ErrorIs(t, err, mongorepository.ErrFailedToCreateIndex)
+	require.ErrorIs(t, err, mongorepository.ErrTextIndexExists)
+	assert.Contains(t, err.Error(), `already has text index "articles_search"`)
+	assert.False(t, findIndex(t, "articles_body_search"))
+}
+
+func TestCreateFullTextIndexTwice(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+		Bio  string             `bson:"bio"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	keys := map[string]int32{"name": 10, "bio": 1}
+	require.NoError(t, repo.CreateFullTextIndex(context.Background(), keys, "english"))
+
+	// The same index again is a no-op
+	require.NoError(t, repo.CreateFullTextIndex(context.Background(), keys, "english"))
+
+	// Another text index conflicts with the existing one
+	err := repo.CreateFullTextIndex(context.Background(), map[string]int32{"name": 1, "bio": 1}, "english")
+	require.ErrorIs(t, err, mongorepository.ErrTextIndexExists)
+	err = repo.CreateFullTextIndex(context.Background(), map[string]int32{"bio": 1}, "spanish")
+	require.ErrorIs(t, err, mongorepository.ErrTextIndexExists)
+}
+
+func TestSearchOptions(t *testing.T) {
+	type Place struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Place](db, "places")
+	require.NoError(t, repo.CreateFullTextIndex(context.Background(), map[string]int32{"name": 1}, "english"))
+
+	for _, name := range []string{"Paris Cafe", "paris bakery", "Café de Flore"} {
+		_, err := repo.Create(context.Background(), Place{Name: name})
+		require.NoError(t, err)
+	}
+	names := func(places []Place) []string {
+		result := make([]string, 0, len(places))
+		for _, p := range places {
+			result = append(result, p.Name)
+		}
+		return result
+	}
+
+	t.Run("CaseSensitive", func(t *testing.T) {
+		places, err := repo.Search(context.Background(), 0, 10, "Paris")
+		require.NoError(t, err)
+		assert.Len(t, places, 2)
+
+		places, err = repo.Search(context.Background(), 0, 10, "Paris", mongorepository.SearchOptions{CaseSensitive: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Paris Cafe"}, names(places))
+
+		_, err = repo.Search(context.Background(), 0, 10, "PARIS", mongorepository.SearchOptions{CaseSensitive: true})
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+	})
+
+	t.Run("DiacriticSensitive", func(t *testing.T) {
+		places, err := repo.Search(context.Background(), 0, 10, "café")
+		require.NoError(t, err)
+		assert.Len(t, places, 2)
+
+		places, err = repo.Search(context.Background(), 0, 10, "café", mongorepository.SearchOptions{DiacriticSensitive: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Café de Flore"}, names(places))
+	})
+
+	t.Run("Language", func(t *testing.T) {
+		// "de" is a stop word in French, so nothing is searched
+		_, err := repo.Search(context.Background(), 0, 10, "de", mongorepository.SearchOptions{Language: "french"})
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+
+		places, err := repo.Search(context.Background(), 0, 10, "de")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Café de Flore"}, names(places))
+	})
+
+	t.Run("Phrase", func(t *testing.T) {
+		places, err := repo.SearchWithScores(context.Background(), 0, 10, `"paris bakery"`, mongorepository.SearchOptions{CaseSensitive: true})
+		require.NoError(t, err)
+		require.Len(t, places, 1)
+		assert.Equal(t, "paris bakery", places[0].Doc.Name)
+	})
+
+	t.Run("TooManyOptions", func(t *testing.T) {
+		_, err := repo.Search(context.Background(), 0, 10, "paris",
+			mongorepository.SearchOptions{CaseSensitive: true}, mongorepository.SearchOptions{Language: "french"})
+		require.ErrorIs(t, err, mongorepository.ErrTooManySearchOptions)
+	})
+}
+
+func TestSearchPipeline(t *testing.T) {
+	type Product struct {
+		ID         primitive.ObjectID `bson:"_id,omitempty"`
+		Name       string             `bson:"name"`
+		Popularity float64            `bson:"popularity"`
+		Score      float64            `bson:"score,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Product](db, "products")
+	require.NoError(t, repo.CreateFullTextIndex(context.Background(), map[string]int32{"name": 1}, "english"))
+
+	for _, product := range []Product{
+		{Name: "coffee mug", Popularity: 1},
+		{Name: "coffee beans", Popularity: 10},
+		{Name: "tea pot", Popularity: 100},
+	} {
+		_, err := repo.Create(context.Background(), product)
+		require.NoError(t, err)
+	}
+
+	// The text score boosted by the popularity
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$text": bson.M{"$search": "coffee"}}}},
+		{{Key: "$addFields", Value: bson.M{"score": bson.M{"$multiply": bson.A{
+			bson.M{"$meta": "textScore"}, "$popularity",
+		}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}}}},
+	}
+
+	products, err := repo.SearchPipeline(context.Background(), pipeline)
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, "coffee beans", products[0].Name)
+	assert.Equal(t, "coffee mug", products[1].Name)
+	assert.Greater(t, products[0].Score, products[1].Score)
+	assert.InDelta(t, products[0].Score/10, products[1].Score, 1e-9)
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := repo.SearchPipeline(context.Background(), mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"$text": bson.M{"$search": "juice"}}}},
+		})
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+	})
+
+	t.Run("NoTextIndex", func(t *testing.T) {
+		other := mongorepository.NewMongoRepository[Product](db, "other_products")
+		_, err := other.Create(context.Background(), Product{Name: "coffee mug"})
+		require.NoError(t, err)
+		_, err = other.SearchPipeline(context.Background(), pipeline)
+		require.ErrorIs(t, err, mongorepository.ErrNoTextIndex)
+	})
+}
+
+func TestSanitizeSearchTerm(t *testing.T) {
+	tests := []struct {
+		name string
+		term string
+		want string
+	}{
+		{"Plain", "  coffee   beans ", "coffee beans"},
+		{"Phrase", `"coffee beans" fresh`, `"coffee beans" fresh`},
+		{"PhraseWhitespace", `"  coffee   beans "`, `"coffee beans"`},
+		{"StrayQuote", `coffee "beans`, "coffee beans"},
+		{"StrayQuoteAfterPhrase", `"coffee beans" fresh"`, `"coffee beans" fresh`},
+		{"EmptyPhrase", `coffee ""`, "coffee"},
+		{"EscapedQuote", `coffee \"beans\"`, `coffee "beans"`},
+		{"Exclusion", "coffee -decaf", "coffee -decaf"},
+		{"RepeatedDashes", "coffee --decaf", "coffee -decaf"},
+		{"LoneDash", "coffee - decaf", "coffee decaf"},
+		{"Hyphenated", "pre-market", "pre-market"},
+		{"ExcludedPhrase", `coffee -"instant coffee"`, `coffee -"instant coffee"`},
+		{"DashBeforeQuoteInWord", `coffee-"beans"`, `coffee "beans"`},
+		{"TrailingDash", "coffee- beans--", "coffee beans"},
+		{"Empty", ` " - `, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mongorepository.SanitizeSearchTerm(tt.term))
+		})
+	}
+}
+
+func TestSearchSort(t *testing.T) {
+	type Article struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Title     string             `bson:"title"`
+		CreatedAt time.Time          `bson:"created_at"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Article](db, "articles")
+	require.NoError(t, repo.CreateFullTextIndex(context.Background(), map[string]int32{"title": 1}, "english"))
+
+	// All the articles have the same text score
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, i := range []int{3, 0, 4, 1, 2} {
+		_, err := repo.Create(context.Background(), Article{
+			Title:     "mongodb news",
+			CreatedAt: now.Add(time.Duration(i) * time.Hour),
+		})
+		require.NoError(t, err)
+	}
+
+	opts := mongorepository.SearchOptions{Sort: bson.D{{Key: "created_at", Value: -1}}}
+	var pages []Article
+	for skip := int64(0); skip < 5; skip += 2 {
+		page, err := repo.Search(context.Background(), skip, 2, "mongodb", opts)
+		require.NoError(t, err)
+		pages = append(pages, page...)
+	}
+	require.Len(t, pages, 5)
+	for i, article := range pages {
+		assert.Equal(t, now.Add(time.Duration(4-i)*time.Hour), article.CreatedAt)
+	}
+
+	// The scores are returned along with the same order
+	scored, err := repo.SearchWithScores(context.Background(), 0, 5, "mongodb", opts)
+	require.NoError(t, err)
+	require.Len(t, scored, 5)
+	assert.Equal(t, now.Add(4*time.Hour), scored[0].Doc.CreatedAt)
+	assert.Equal(t, scored[0].Score, scored[4].Score)
+}
+
+func TestUpdateTextIndexWeights(t *testing.T) {
+	type Article struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Title string             `bson:"title"`
+		Body  string             `bson:"body"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Article](db, "articles")
+	_, err := repo.Create(context.Background(), Article{Title: "MongoDB", Body: "text search"})
+	require.NoError(t, err)
+
+	// textIndex returns the spec of the text index
+	type textIndexSpec struct {
+		Name             string           `bson:"name"`
+		Weights          map[string]int32 `bson:"weights"`
+		DefaultLanguage  string           `bson:"default_language"`
+		LanguageOverride string           `bson:"language_override"`
+	}
+	textIndex := func() textIndexSpec {
+		cursor, err := db.Collection("articles").Indexes().List(context.Background())
+		require.NoError(t, err)
+		var specs []bson.Raw
+		require.NoError(t, cursor.All(context.Background(), &specs))
+		var text []textIndexSpec
+		for _, raw := range specs {
+			if fts, ok := raw.Lookup("key", "_fts").StringValueOK(); !ok || fts != "text" {
+				continue
+			}
+			var spec textIndexSpec
+			require.NoError(t, bson.Unmarshal(raw, &spec))
+			text = append(text, spec)
+		}
+		require.Len(t, text, 1)
+		return text[0]
+	}
+
+	weights := map[string]int32{"title": 10, "body": 1}
+	require.NoError(t, repo.UpdateTextIndexWeights(context.Background(), weights, "english"))
+	created := textIndex()
+	assert.Equal(t, weights, created.Weights)
+	assert.Equal(t, "english_fts_index", created.Name)
+
+	// Identical weights are a no-op
+	require.NoError(t, repo.UpdateTextIndexWeights(context.Background(), weights, "english"))
+	assert.Equal(t, created, textIndex())
+
+	// Changed weights recreate the index
+	newWeights := map[string]int32{"title": 5, "body": 2}
+	require.NoError(t, repo.UpdateTextIndexWeights(context.Background(), newWeights, "english"))
+	assert.Equal(t, textIndexSpec{
+		Name:             "english_fts_index",
+		Weights:          newWeights,
+		DefaultLanguage:  "english",
+		LanguageOverride: "language",
+	}, textIndex())
+
+	// A default name follows the new language
+	require.NoError(t, repo.UpdateTextIndexWeights(context.Background(), newWeights, "french"))
+	assert.Equal(t, "french_fts_index", textIndex().Name)
+
+	articles, err := repo.Search(context.Background(), 0, 10, "mongodb")
+	require.NoError(t, err)
+	assert.Len(t, articles, 1)
+
+	t.Run("CustomIndex", func(t *testing.T) {
+		require.NoError(t, repo.DropIndex(context.Background(), "french_fts_index"))
+		require.NoError(t, repo.CreateTextIndex(context.Background(), mongorepository.TextIndexConfig{
+			Fields:           weights,
+			DefaultLang:      "english",
+			Name:             "articles_search",
+			LanguageOverride: "lang",
+		}))
+
+		// The custom name and language override are kept
+		require.NoError(t, repo.UpdateTextIndexWeights(context.Background(), newWeights, "english"))
+		assert.Equal(t, textIndexSpec{
+			Name:             "articles_search",
+			Weights:          newWeights,
+			DefaultLanguage:  "english",
+			LanguageOverride: "lang",
+		}, textIndex())
+	})
 }
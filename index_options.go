@@ -60,6 +60,16 @@ func SetCollation(collation *options.Collation) IndexOption {
 	}
 }
 
+// UniqueCI specifies a case-insensitive unique index, e.g. for emails, so "A@x.com" and "a@x.com" collide.
+// It sets the unique option and the {locale: "en", strength: 2} collation. Note that only the queries
+// with the same collation, e.g. set by WithCollation, use the index and match case-insensitively.
+func UniqueCI() IndexOption {
+	return func(opts *options.IndexOptions) {
+		opts.SetUnique(true)
+		opts.SetCollation(&options.Collation{Locale: "en", Strength: 2})
+	}
+}
+
 // SetWildcardProjection specifies the wildcardProjection option for an index
 func SetWildcardProjection(wildcardProjection interface{}) IndexOption {
 	return func(opts *options.IndexOptions) {
@@ -76,9 +86,10 @@ func SetHidden(hidden bool) IndexOption {
 
 // TextIndexConfig configures the text index
 type TextIndexConfig struct {
-	Fields      map[string]int32 // Fields with weights
-	DefaultLang string           // Default language for the index
-	Name        string           // Optional custom name for the index
+	Fields           map[string]int32 // Fields with weights
+	DefaultLang      string           // Default language for the index
+	Name             string           // Optional custom name for the index
+	LanguageOverride string           // Optional document field holding the per-document language, "language" by default
 }
 
 // NewTextIndexConfig creates a new text index config with specified fields and weights
@@ -115,5 +126,10 @@ func TextIndex(config TextIndexConfig) IndexOption {
 		if config.Name != "" {
 			opts.SetName(config.Name)
 		}
+
+		// Set the language override field if provided
+		if config.LanguageOverride != "" {
+			opts.SetLanguageOverride(config.LanguageOverride)
+		}
 	}
 }
@@ -0,0 +1,252 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexInfo describes an index of the MongoDB collection.
+type IndexInfo struct {
+	Name   string        // Name of the index
+	Keys   bson.D        // Indexed fields with their order or type
+	Unique bool          // Whether the index enforces uniqueness
+	TTL    time.Duration // Time-To-Live of the documents, zero if not a TTL index
+}
+
+// IndexKey is a field of a compound index with its sort order.
+type IndexKey struct {
+	Field string // Name of the indexed field
+	Order int    // 1 for ascending, -1 for descending order
+}
+
+// indexSpec is the raw index specification returned by the listIndexes command.
+type indexSpec struct {
+	Name               string `bson:"name"`
+	Key                bson.D `bson:"key"`
+	Unique             bool   `bson:"unique,omitempty"`
+	Sparse             bool   `bson:"sparse,omitempty"`
+	ExpireAfterSeconds *int64 `bson:"expireAfterSeconds,omitempty"`
+	// Text index options
+	Weights          map[string]int32 `bson:"weights,omitempty"`
+	DefaultLanguage  string           `bson:"default_language,omitempty"`
+	LanguageOverride string           `bson:"language_override,omitempty"`
+}
+
+// Error codes returned by MongoDB when an index conflicts with an existing one.
+const (
+	indexOptionsConflictCode  = 85
+	indexKeySpecsConflictCode = 86
+)
+
+// CreateCompoundIndex creates an index on several fields, each with its own sort order, e.g.
+// {status: 1, created_at: -1} to serve queries filtering by status and sorted by the newest first.
+// It takes a context.Context, the index keys and optional IndexOption(s).
+// The function returns an error if the index creation fails.
+func (r *mongoRepository[T]) CreateCompoundIndex(ctx context.Context, keys []IndexKey, opts ...IndexOption) (err error) {
+	ctx, op := r.startOperation(ctx, "CreateCompoundIndex")
+	defer func() { op.end(err) }()
+
+	if len(keys) == 0 {
+		return errors.Join(ErrFailedToCreateIndex, ErrNoIndexKeys)
+	}
+
+	indexOpts := options.Index()
+	for _, opt := range opts {
+		opt(indexOpts)
+	}
+
+	indexKeys := make(bson.D, 0, len(keys))
+	for _, k := range keys {
+		order := 1
+		if k.Order < 0 {
+			order = -1
+		}
+		indexKeys = append(indexKeys, bson.E{Key: k.Field, Value: order})
+	}
+
+	indexModel := mongo.IndexModel{
+		Keys:    indexKeys,
+		Options: indexOpts,
+	}
+
+	if _, err := r.collection.Indexes().CreateOne(ctx, indexModel); err != nil {
+		return errors.Join(ErrFailedToCreateIndex, err)
+	}
+	return nil
+}
+
+// CreateSoftDeleteAwareUniqueIndex creates an index enforcing the uniqueness of the field
+// among the documents which are not soft-deleted, i.e. don't have the deletedField set,
+// so that e.g. an email of a soft-deleted user can be registered again.
+//
+// MongoDB doesn't support {$exists: false} in partial filter expressions, so instead of a partial index
+// it creates a unique compound index on {field: 1, deletedField: 1}: active documents miss the deletedField
+// and collide on the field value, while soft-deleted ones are told apart by the deletion timestamp.
+// As a consequence, two documents with the same value soft-deleted at the same time also collide.
+// The function returns an error if the index creation fails.
+func (r *mongoRepository[T]) CreateSoftDeleteAwareUniqueIndex(ctx context.Context, field, deletedField string) (err error) {
+	ctx, op := r.startOperation(ctx, "CreateSoftDeleteAwareUniqueIndex")
+	defer func() { op.end(err) }()
+
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: field, Value: 1}, {Key: deletedField, Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetName(field + "_active_unique"),
+	}
+
+	if _, err := r.collection.Indexes().CreateOne(ctx, indexModel); err != nil {
+		return errors.Join(ErrFailedToCreateIndex, err)
+	}
+	return nil
+}
+
+// ListIndexes returns all indexes of the MongoDB collection, including the default _id index.
+// It takes a context.Context as the only argument.
+// The function returns a slice of IndexInfo and an error, if any.
+func (r *mongoRepository[T]) ListIndexes(ctx context.Context) (_ []IndexInfo, err error) {
+	ctx, op := r.startOperation(ctx, "ListIndexes")
+	defer func() { op.end(err) }()
+
+	cursor, err := r.collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, errors.Join(ErrFailedToListIndexes, err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []IndexInfo
+	for cursor.Next(ctx) {
+		var spec indexSpec
+		if err := cursor.Decode(&spec); err != nil {
+			return nil, errors.Join(ErrFailedToListIndexes, err)
+		}
+		info := IndexInfo{
+			Name:   spec.Name,
+			Keys:   spec.Key,
+			Unique: spec.Unique,
+		}
+		if spec.ExpireAfterSeconds != nil {
+			info.TTL = time.Duration(*spec.ExpireAfterSeconds) * time.Second
+		}
+		results = append(results, info)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, errors.Join(ErrFailedToListIndexes, err)
+	}
+
+	return results, nil
+}
+
+// DropIndex drops the index with the given name from the MongoDB collection.
+// It takes a context.Context and the name of the index as parameters.
+// The function returns an error if the index deletion fails.
+func (r *mongoRepository[T]) DropIndex(ctx context.Context, name string) (err error) {
+	ctx, op := r.startOperation(ctx, "DropIndex")
+	defer func() { op.end(err) }()
+
+	if _, err := r.collection.Indexes().DropOne(ctx, name); err != nil {
+		return errors.Join(ErrFailedToDropIndex, err)
+	}
+	return nil
+}
+
+// EnsureIndex creates an index in the MongoDB collection based on the specified key and options,
+// the same way as CreateIndex, but it is safe to call on every application start.
+// If an index with the same key already exists and matches the requested unique, sparse and TTL options,
+// the conflict is ignored. Otherwise, the conflicting index is dropped and recreated with the new options.
+// The function returns an error if the index creation fails.
+func (r *mongoRepository[T]) EnsureIndex(ctx context.Context, key string, opts ...IndexOption) (err error) {
+	ctx, op := r.startOperation(ctx, "EnsureIndex")
+	defer func() { op.end(err) }()
+
+	indexOpts := options.Index()
+	for _, opt := range opts {
+		opt(indexOpts)
+	}
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: key, Value: 1}},
+		Options: indexOpts,
+	}
+
+	_, err = r.collection.Indexes().CreateOne(ctx, indexModel)
+	if err == nil {
+		return nil
+	}
+	if !isIndexConflictError(err) {
+		return errors.Join(ErrFailedToCreateIndex, err)
+	}
+
+	// Find the conflicting indexes: the ones with the same key or the same name
+	specs, err := r.listIndexSpecs(ctx)
+	if err != nil {
+		return errors.Join(ErrFailedToCreateIndex, err)
+	}
+	var conflicts []indexSpec
+	for _, spec := range specs {
+		sameKey := len(spec.Key) == 1 && spec.Key[0].Key == key
+		sameName := indexOpts.Name != nil && spec.Name == *indexOpts.Name
+		if sameKey && indexSpecMatches(spec, indexOpts) {
+			return nil
+		}
+		if sameKey || sameName {
+			conflicts = append(conflicts, spec)
+		}
+	}
+
+	// Drop the conflicting indexes and recreate the index with the requested options
+	for _, spec := range conflicts {
+		if _, err := r.collection.Indexes().DropOne(ctx, spec.Name); err != nil {
+			return errors.Join(ErrFailedToCreateIndex, ErrFailedToDropIndex, err)
+		}
+	}
+	if _, err := r.collection.Indexes().CreateOne(ctx, indexModel); err != nil {
+		return errors.Join(ErrFailedToCreateIndex, err)
+	}
+	return nil
+}
+
+// listIndexSpecs returns the raw index specifications of the MongoDB collection.
+func (r *mongoRepository[T]) listIndexSpecs(ctx context.Context) ([]indexSpec, error) {
+	cursor, err := r.collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var specs []indexSpec
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// indexSpecMatches reports whether the existing index has the same unique, sparse and TTL options as requested.
+func indexSpecMatches(spec indexSpec, opts *options.IndexOptions) bool {
+	if spec.Unique != (opts.Unique != nil && *opts.Unique) {
+		return false
+	}
+	if spec.Sparse != (opts.Sparse != nil && *opts.Sparse) {
+		return false
+	}
+	if (spec.ExpireAfterSeconds == nil) != (opts.ExpireAfterSeconds == nil) {
+		return false
+	}
+	if spec.ExpireAfterSeconds != nil && *spec.ExpireAfterSeconds != int64(*opts.ExpireAfterSeconds) {
+		return false
+	}
+	return true
+}
+
+// isIndexConflictError reports whether the error is caused by a conflict with an existing index.
+func isIndexConflictError(err error) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	return serverErr.HasErrorCode(indexOptionsConflictCode) || serverErr.HasErrorCode(indexKeySpecsConflictCode)
+}
@@ -0,0 +1,201 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestIndexes(t *testing.T) {
+	type Session struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Token     string             `bson:"token"`
+		CreatedAt time.Time          `bson:"created_at"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Session](db, "sessions")
+
+	findIndex := func(t *testing.T, name string) (mongorepository.IndexInfo, bool) {
+		indexes, err := repo.ListIndexes(context.Background())
+		require.NoError(t, err)
+		for _, idx := range indexes {
+			if idx.Name == name {
+				return idx, true
+			}
+		}
+		return mongorepository.IndexInfo{}, false
+	}
+
+	t.Run("ListIndexes", func(t *testing.T) {
+		require.NoError(t, repo.CreateIndex(
+			context.Background(),
+			"token",
+			mongorepository.Name("token_unique"),
+			mongorepository.Unique(true),
+		))
+		require.NoError(t, repo.CreateIndex(
+			context.Background(),
+			"created_at",
+			mongorepository.Name("created_at_ttl"),
+			mongorepository.TTL(time.Hour),
+		))
+
+		idx, ok := findIndex(t, "token_unique")
+		require.True(t, ok)
+		assert.True(t, idx.Unique)
+		assert.Equal(t, "token", idx.Keys[0].Key)
+
+		idx, ok = findIndex(t, "created_at_ttl")
+		require.True(t, ok)
+		assert.False(t, idx.Unique)
+		assert.Equal(t, time.Hour, idx.TTL)
+	})
+
+	t.Run("KeysDocument", func(t *testing.T) {
+		var r mongorepository.Repository[Session] = repo
+		require.NoError(t, r.CreateIndex(
+			context.Background(),
+			bson.D{{Key: "token", Value: 1}, {Key: "created_at", Value: -1}},
+			mongorepository.Name("token_created_at"),
+		))
+
+		idx, ok := findIndex(t, "token_created_at")
+		require.True(t, ok)
+		assert.Equal(t, bson.D{{Key: "token", Value: int32(1)}, {Key: "created_at", Value: int32(-1)}}, idx.Keys)
+	})
+
+	t.Run("DropIndex", func(t *testing.T) {
+		require.NoError(t, repo.DropIndex(context.Background(), "token_unique"))
+
+		_, ok := findIndex(t, "token_unique")
+		assert.False(t, ok)
+
+		// Dropping a non-existent index fails
+		err := repo.DropIndex(context.Background(), "token_unique")
+		require.ErrorIs(t, err, mongorepository.ErrFailedToDropIndex)
+	})
+
+	t.Run("EnsureIndex", func(t *testing.T) {
+		// Calling it twice with the same spec is a no-op
+		for i := 0; i < 2; i++ {
+			require.NoError(t, repo.EnsureIndex(
+				context.Background(),
+				"token",
+				mongorepository.Name("token_idx"),
+				mongorepository.Unique(true),
+			))
+		}
+
+		// Same key with a different name is treated as the existing index
+		require.NoError(t, repo.EnsureIndex(
+			context.Background(),
+			"token",
+			mongorepository.Name("token_other_name"),
+			mongorepository.Unique(true),
+		))
+		idx, ok := findIndex(t, "token_idx")
+		require.True(t, ok)
+		assert.True(t, idx.Unique)
+
+		// Different options cause the index to be recreated
+		require.NoError(t, repo.EnsureIndex(
+			context.Background(),
+			"token",
+			mongorepository.Name("token_idx"),
+			mongorepository.Unique(false),
+		))
+		idx, ok = findIndex(t, "token_idx")
+		require.True(t, ok)
+		assert.False(t, idx.Unique)
+	})
+
+	t.Run("CreateCompoundIndex", func(t *testing.T) {
+		require.NoError(t, repo.CreateCompoundIndex(
+			context.Background(),
+			[]mongorepository.IndexKey{
+				{Field: "status", Order: 1},
+				{Field: "created_at", Order: -1},
+			},
+			mongorepository.Name("status_created_at"),
+		))
+
+		idx, ok := findIndex(t, "status_created_at")
+		require.True(t, ok)
+		assert.Equal(t, bson.D{
+			{Key: "status", Value: int32(1)},
+			{Key: "created_at", Value: int32(-1)},
+		}, idx.Keys)
+
+		// At least one key is required
+		err := repo.CreateCompoundIndex(context.Background(), nil)
+		require.ErrorIs(t, err, mongorepository.ErrNoIndexKeys)
+	})
+}
+
+func TestSoftDeleteAwareUniqueIndex(t *testing.T) {
+	type User struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Email     string             `bson:"email"`
+		DeletedAt *time.Time         `bson:"deleted_at,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	require.NoError(t, repo.CreateSoftDeleteAwareUniqueIndex(context.Background(), "email", "deleted_at"))
+
+	email := "john@example.com"
+	id, err := repo.Create(context.Background(), User{Email: email})
+	require.NoError(t, err)
+
+	// Two active documents collide
+	_, err = repo.Create(context.Background(), User{Email: email})
+	require.ErrorIs(t, err, mongorepository.ErrDuplicate)
+
+	// Soft-delete the first document
+	objID, err := primitive.ObjectIDFromHex(id)
+	require.NoError(t, err)
+	_, err = repo.UpdateMany(
+		context.Background(),
+		map[string]interface{}{"deleted_at": time.Now()},
+		mongorepository.Eq("_id", objID),
+	)
+	require.NoError(t, err)
+
+	// The email of the soft-deleted document can be reused
+	_, err = repo.Create(context.Background(), User{Email: email})
+	require.NoError(t, err)
+}
+
+func TestUniqueCI(t *testing.T) {
+	type User struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Email string             `bson:"email"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	require.NoError(t, repo.CreateIndex(context.Background(), "email", mongorepository.UniqueCI()))
+
+	_, err := repo.Create(context.Background(), User{Email: "A@x.com"})
+	require.NoError(t, err)
+	_, err = repo.Create(context.Background(), User{Email: "a@x.com"})
+	require.ErrorIs(t, err, mongorepository.ErrDuplicate)
+	_, err = repo.Create(context.Background(), User{Email: "b@x.com"})
+	require.NoError(t, err)
+
+	// Queries with the index collation match case-insensitively
+	ctx := mongorepository.WithQueryOptions(context.Background(),
+		mongorepository.WithCollation(&options.Collation{Locale: "en", Strength: 2}))
+	user, err := repo.FindOneByFilter(ctx, mongorepository.Eq("email", "a@X.COM"))
+	require.NoError(t, err)
+	assert.Equal(t, "A@x.com", user.Email)
+}
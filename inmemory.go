@@ -0,0 +1,1248 @@
+package mongorepository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// inMemoryRepository is a Repository backed by a Go map instead of a MongoDB collection, for unit tests.
+// The documents are stored as BSON, so they go through the same struct tags and encoding as with MongoDB.
+type inMemoryRepository[T any] struct {
+	mu    sync.RWMutex
+	docs  map[string]bson.D
+	order []string
+	opts  repositoryOptions
+}
+
+// NewInMemoryRepository creates a new repository keeping the documents in memory, e.g. to unit test
+// the code depending on Repository[T] without running MongoDB. The options WithQueryHook, WithDefaultLimit,
+// WithMaxLimit and WithEmptyResultNil work the same way as with NewMongoRepository; the other ones,
+// e.g. WithDefaultTimeout, WithTracer and WithRetry, have no effect.
+//
+// It supports the CRUD operations, counting and the filters built by Eq, Ne, Gt, Gte, Lt, Lte, In, Exists,
+// IsNull, IsMissing, And, Or and Not, on top-level and embedded document fields given in dot notation.
+// Array fields match if any of their elements matches, as with MongoDB, but paths traversing arrays,
+// e.g. "orders.items.sku", are not resolved. The sort set by WithSort is applied to the found documents;
+// the other query options are ignored. The documents are returned in the insertion order otherwise.
+// Filters with other operators fail with the ErrUnsupportedFilter error. Index operations are no-ops,
+// so unique indexes are not enforced. It doesn't implement SearchableRepository, and the following methods
+// fail with the ErrNotSupported error: CreateIdempotent, FindByObjectIDsMap, FindByIDsOrdered, UpdateAndReturn,
+// UpdateVersioned, UpdateManyOps, UpsertMany, BulkWrite, CopyField, RenameField, NormalizeStringField,
+// FindDuplicatesOf, FindOneWithSlice, FindManyWithSize, ListIndexes, DistinctWithCounts, MaxTime, MinTime,
+// Facet, Sum, Avg, CountByTimeBucket, CountByPeriod, JoinStream, Aggregate, Explain, Stats, Tail, Watch
+// and WatchFrom. Use the MongoDB repository to test them.
+func NewInMemoryRepository[T any](opts ...Option) *inMemoryRepository[T] {
+	repo := &inMemoryRepository[T]{docs: make(map[string]bson.D)}
+	for _, opt := range opts {
+		opt(&repo.opts)
+	}
+	return repo
+}
+
+// startOperation is called at the beginning of every repository operation, so the query hook is called
+// the same way as with the MongoDB repository. Neither the default timeout nor the tracer are applied.
+func (r *inMemoryRepository[T]) startOperation(ctx context.Context, name string) (context.Context, *operation) {
+	return ctx, &operation{name: name, start: time.Now(), opts: &r.opts}
+}
+
+// notSupported returns the error of a method the in-memory repository doesn't implement.
+func notSupported(method string) error {
+	return fmt.Errorf("%w: %s", ErrNotSupported, method)
+}
+
+// ForDatabase returns a new empty in-memory repository with the same options, the same way
+// a repository on another database doesn't see the documents of this one.
+func (r *inMemoryRepository[T]) ForDatabase(db *mongo.Database) Repository[T] {
+	return &inMemoryRepository[T]{docs: make(map[string]bson.D), opts: r.opts}
+}
+
+// Ping always succeeds, since there is no database to reach.
+func (r *inMemoryRepository[T]) Ping(ctx context.Context) (err error) {
+	_, op := r.startOperation(ctx, "Ping")
+	defer func() { op.end(err) }()
+	return nil
+}
+
+// CreateIndex does nothing, since the in-memory repository has no indexes.
+func (r *inMemoryRepository[T]) CreateIndex(ctx context.Context, key interface{}, opts ...IndexOption) error {
+	return nil
+}
+
+// CreateCompoundIndex does nothing, since the in-memory repository has no indexes.
+func (r *inMemoryRepository[T]) CreateCompoundIndex(ctx context.Context, keys []IndexKey, opts ...IndexOption) error {
+	return nil
+}
+
+// CreateSoftDeleteAwareUniqueIndex does nothing, since the in-memory repository has no indexes.
+func (r *inMemoryRepository[T]) CreateSoftDeleteAwareUniqueIndex(ctx context.Context, field, deletedField string) error {
+	return nil
+}
+
+// EnsureIndex does nothing, since the in-memory repository has no indexes.
+func (r *inMemoryRepository[T]) EnsureIndex(ctx context.Context, key string, opts ...IndexOption) error {
+	return nil
+}
+
+// DropIndex does nothing, since the in-memory repository has no indexes.
+func (r *inMemoryRepository[T]) DropIndex(ctx context.Context, name string) error {
+	return nil
+}
+
+// Create stores a copy of the model, generating an ObjectID if the model has no _id.
+// It returns the ID of the new document and an error with the ErrDuplicate error code
+// if a document with the same _id already exists.
+func (r *inMemoryRepository[T]) Create(ctx context.Context, model T) (_ string, err error) {
+	_, op := r.startOperation(ctx, "Create")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return "", errors.Join(ErrFailedToCreate, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.insert(model)
+}
+
+// insert stores the model and returns its ID. The caller must hold the write lock.
+func (r *inMemoryRepository[T]) insert(model T) (string, error) {
+	doc, err := toBsonD(model)
+	if err != nil {
+		return "", errors.Join(ErrFailedToCreate, err)
+	}
+	idValue, ok := lookupField(doc, "_id")
+	if !ok {
+		idValue = primitive.NewObjectID()
+		doc = append(bson.D{{Key: "_id", Value: idValue}}, doc...)
+	}
+	id, ok := idString(idValue)
+	if !ok {
+		return "", errors.Join(ErrFailedToCreate, ErrInvalidDocumentID, fmt.Errorf("unsupported _id type %T", idValue))
+	}
+	if _, exists := r.docs[id]; exists {
+		return "", errors.Join(ErrFailedToCreate, ErrDuplicate)
+	}
+	r.docs[id] = doc
+	r.order = append(r.order, id)
+	return id, nil
+}
+
+// CreateUnacknowledged stores the model the same way as Create, since there are no writes to wait for.
+func (r *inMemoryRepository[T]) CreateUnacknowledged(ctx context.Context, model T) error {
+	_, err := r.Create(ctx, model)
+	return err
+}
+
+// CreateIdempotent is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) CreateIdempotent(ctx context.Context, model T, idempotencyKey string) (string, bool, error) {
+	return "", false, notSupported("CreateIdempotent")
+}
+
+// FindOrCreate returns the first document matching the filters, storing the model if there is none.
+// It returns the found or created document, whether it was created and an error, if any.
+func (r *inMemoryRepository[T]) FindOrCreate(ctx context.Context, model T, filters ...FilterFunc) (_ T, created bool, err error) {
+	ctx, op := r.startOperation(ctx, "FindOrCreate")
+	defer func() { op.end(err) }()
+
+	var result T
+	if err := validate(&model); err != nil {
+		return result, false, errors.Join(ErrFailedToCreate, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	docs, err := r.find(ctx, filters)
+	if err != nil {
+		return result, false, errors.Join(ErrFailedToCreate, err)
+	}
+	if len(docs) > 0 {
+		result, err = decodeDoc[T](docs[0])
+		if err != nil {
+			return result, false, errors.Join(ErrFailedToCreate, err)
+		}
+		return result, false, nil
+	}
+	id, err := r.insert(model)
+	if err != nil {
+		return result, false, err
+	}
+	result, err = decodeDoc[T](r.docs[id])
+	if err != nil {
+		return result, false, errors.Join(ErrFailedToCreate, err)
+	}
+	return result, true, nil
+}
+
+// FindByID returns the document with the specified ID.
+// If the document doesn't exist, it returns an error with the ErrNotFound error code.
+func (r *inMemoryRepository[T]) FindByID(ctx context.Context, id string) (_ T, err error) {
+	_, op := r.startOperation(ctx, "FindByID")
+	defer func() { op.end(err) }()
+
+	var result T
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return result, errors.Join(ErrFailedToFindByID, ErrInvalidDocumentID, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	doc, ok := r.docs[id]
+	if !ok {
+		return result, errors.Join(ErrFailedToFindByID, ErrNotFound)
+	}
+	result, err = decodeDoc[T](doc)
+	if err != nil {
+		return result, errors.Join(ErrFailedToFindByID, err)
+	}
+	return result, nil
+}
+
+// FindByIDs returns the documents with the specified IDs in the insertion order, each once.
+// If none of the documents exist, it returns an error with the ErrNotFound error code,
+// or an empty slice if the repository is created with WithEmptyResultNil.
+func (r *inMemoryRepository[T]) FindByIDs(ctx context.Context, ids ...string) (_ []T, err error) {
+	_, op := r.startOperation(ctx, "FindByIDs")
+	defer func() { op.end(err) }()
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			return nil, errors.Join(ErrFailedToFindByIDs, ErrInvalidDocumentID, err)
+		}
+		wanted[id] = true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var results []T
+	for _, id := range r.order {
+		if !wanted[id] {
+			continue
+		}
+		result, err := decodeDoc[T](r.docs[id])
+		if err != nil {
+			return nil, errors.Join(ErrFailedToFindByIDs, err)
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		if r.opts.emptyResultNil{
+			return []T{}, nil
+		}
+		return nil, errors.Join(ErrFailedToFindByIDs, ErrNotFound)
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// FindByObjectIDsMap is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) FindByObjectIDsMap(ctx context.Context, ids ...primitive.ObjectID) (map[primitive.ObjectID]T, error) {
+	return nil, notSupported("FindByObjectIDsMap")
+}
+
+// FindByIDsOrdered is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) FindByIDsOrdered(ctx context.Context, missing MissingIDsPolicy, ids ...string) ([]T, error) {
+	return nil, notSupported("FindByIDsOrdered")
+}
+
+// Update sets the fields of the model on the document with the specified ID, the same way as $set.
+// If the document doesn't exist, it returns an error with the ErrNotFound error code.
+// It returns the number of modified documents and an error, if any.
+func (r *inMemoryRepository[T]) Update(ctx context.Context, id string, model T) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "Update")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return 0, errors.Join(ErrFailedToFindByID, ErrInvalidDocumentID, err)
+	}
+	fields, err := toBsonD(model)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc, ok := r.docs[id]
+	if !ok {
+		return 0, errors.Join(ErrFailedToUpdate, ErrNotFound)
+	}
+	updated := cloneDoc(doc)
+	for _, e := range fields {
+		if e.Key == "_id" {
+			continue
+		}
+		if updated, err = setField(updated, e.Key, e.Value); err != nil {
+			return 0, errors.Join(ErrFailedToUpdate, err)
+		}
+	}
+	return r.store(id, doc, updated)
+}
+
+// Replace replaces the whole document with the specified ID by the model, keeping the _id intact.
+// If the document doesn't exist, it returns an error with the ErrNotFound error code.
+// It returns the number of modified documents and an error, if any.
+func (r *inMemoryRepository[T]) Replace(ctx context.Context, id string, model T) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "Replace")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return 0, errors.Join(ErrFailedToReplace, err)
+	}
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return 0, errors.Join(ErrFailedToReplace, ErrInvalidDocumentID, err)
+	}
+	fields, err := toBsonD(model)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToReplace, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc, ok := r.docs[id]
+	if !ok {
+		return 0, errors.Join(ErrFailedToReplace, ErrNotFound)
+	}
+	idValue, _ := lookupField(doc, "_id")
+	replacement := bson.D{{Key: "_id", Value: idValue}}
+	for _, e := range fields {
+		if e.Key != "_id" {
+			replacement = append(replacement, e)
+		}
+	}
+	return r.store(id, doc, replacement)
+}
+
+// UpdateAndReturn is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) UpdateAndReturn(ctx context.Context, id string, model T) (T, bool, error) {
+	var result T
+	return result, false, notSupported("UpdateAndReturn")
+}
+
+// UpdateVersioned is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) UpdateVersioned(ctx context.Context, id string, model T, expectedVersion int64) (int64, error) {
+	return 0, notSupported("UpdateVersioned")
+}
+
+// UpdateFields sets the given fields of the document with the given ID, leaving the other fields intact.
+// If the document doesn't exist, it returns an error with the ErrNotFound error code.
+// It returns the number of modified documents and an error, if any.
+func (r *inMemoryRepository[T]) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "UpdateFields")
+	defer func() { op.end(err) }()
+
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, ErrInvalidDocumentID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc, ok := r.docs[id]
+	if !ok {
+		return 0, errors.Join(ErrFailedToUpdate, ErrNotFound)
+	}
+	updated, err := setFields(doc, fields)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+	return r.store(id, doc, updated)
+}
+
+// UpdateMany sets the given fields of all documents matching the filters.
+// It returns the number of documents modified and an error if any.
+func (r *inMemoryRepository[T]) UpdateMany(ctx context.Context, update map[string]interface{}, filters ...FilterFunc) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "UpdateMany")
+	defer func() { op.end(err) }()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids, err := r.matchingIDs(filters)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdateMany, err)
+	}
+	var modified int64
+	for _, id := range ids {
+		updated, err := setFields(r.docs[id], update)
+		if err != nil {
+			return modified, errors.Join(ErrFailedToUpdateMany, err)
+		}
+		n, _ := r.store(id, r.docs[id], updated)
+		modified += n
+	}
+	op.setDocumentCount(modified)
+	return modified, nil
+}
+
+// UpdateOneByFilter sets the given fields of the first document matching the filters.
+// If no documents match the filters, it returns an error with the ErrNotFound error code.
+// It returns the number of documents modified and an error if any.
+func (r *inMemoryRepository[T]) UpdateOneByFilter(ctx context.Context, update map[string]interface{}, filters ...FilterFunc) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "UpdateOneByFilter")
+	defer func() { op.end(err) }()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids, err := r.matchingIDs(filters)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+	if len(ids) == 0 {
+		return 0, errors.Join(ErrFailedToUpdate, ErrNotFound)
+	}
+	updated, err := setFields(r.docs[ids[0]], update)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+	return r.store(ids[0], r.docs[ids[0]], updated)
+}
+
+// CopyField is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) CopyField(ctx context.Context, from, to string, filters ...FilterFunc) (int64, error) {
+	return 0, notSupported("CopyField")
+}
+
+// RenameField is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) RenameField(ctx context.Context, from, to string, filters ...FilterFunc) (int64, error) {
+	return 0, notSupported("RenameField")
+}
+
+// NormalizeStringField is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) NormalizeStringField(ctx context.Context, field string, mode StringNormalizeMode, filters ...FilterFunc) (int64, error) {
+	return 0, notSupported("NormalizeStringField")
+}
+
+// CompareAndSet sets the field of the document with the specified ID to newValue,
+// but only if the field currently equals the expected value.
+// It returns true if the value was set and false if the precondition did not hold, and an error, if any.
+func (r *inMemoryRepository[T]) CompareAndSet(ctx context.Context, id string, field string, expected, newValue interface{}) (_ bool, err error) {
+	_, op := r.startOperation(ctx, "CompareAndSet")
+	defer func() { op.end(err) }()
+
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return false, errors.Join(ErrFailedToUpdate, ErrInvalidDocumentID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc, ok := r.docs[id]
+	if !ok {
+		return false, nil
+	}
+	matched, err := matchDoc(doc, bson.D{{Key: field, Value: expected}})
+	if err != nil {
+		return false, errors.Join(ErrFailedToUpdate, err)
+	}
+	if !matched {
+		return false, nil
+	}
+	updated, err := setFields(doc, map[string]interface{}{field: newValue})
+	if err != nil {
+		return false, errors.Join(ErrFailedToUpdate, err)
+	}
+	_, err = r.store(id, doc, updated)
+	return true, err
+}
+
+// Delete deletes the document with the specified ID.
+// If the document doesn't exist, it returns an error with the ErrNotFound error code.
+// It returns the number of deleted documents and an error, if any.
+func (r *inMemoryRepository[T]) Delete(ctx context.Context, id string) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "Delete")
+	defer func() { op.end(err) }()
+
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return 0, errors.Join(ErrFailedToFindByID, ErrInvalidDocumentID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.docs[id]; !ok {
+		return 0, errors.Join(ErrFailedToDelete, ErrNotFound)
+	}
+	r.remove(id)
+	return 1, nil
+}
+
+// DeleteByIDs deletes the documents with the given IDs, ignoring the missing ones.
+// All ids are validated before deleting anything.
+// It returns the number of deleted documents and an error, if any.
+func (r *inMemoryRepository[T]) DeleteByIDs(ctx context.Context, ids ...string) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "DeleteByIDs")
+	defer func() { op.end(err) }()
+
+	for _, id := range ids {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			return 0, errors.Join(ErrFailedToDeleteMany, ErrInvalidDocumentID, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for _, id := range ids {
+		if _, ok := r.docs[id]; ok {
+			r.remove(id)
+			deleted++
+		}
+	}
+	op.setDocumentCount(deleted)
+	return deleted, nil
+}
+
+// DeleteMany deletes all documents matching the filters.
+// It returns the number of deleted documents and an error, if any.
+func (r *inMemoryRepository[T]) DeleteMany(ctx context.Context, filters ...FilterFunc) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "DeleteMany")
+	defer func() { op.end(err) }()
+
+	ids, err := r.deleteMatching(filters)
+	if err != nil {
+		return 0, err
+	}
+	op.setDocumentCount(int64(len(ids)))
+	return int64(len(ids)), nil
+}
+
+// DeleteManyReturningIDs deletes all documents matching the filters and returns their IDs.
+// It returns the IDs of the deleted documents and an error, if any.
+func (r *inMemoryRepository[T]) DeleteManyReturningIDs(ctx context.Context, filters ...FilterFunc) (_ []string, err error) {
+	_, op := r.startOperation(ctx, "DeleteManyReturningIDs")
+	defer func() { op.end(err) }()
+
+	ids, err := r.deleteMatching(filters)
+	if err != nil {
+		return nil, err
+	}
+	op.setDocumentCount(int64(len(ids)))
+	return ids, nil
+}
+
+// deleteMatching deletes the documents matching the filters and returns their IDs.
+func (r *inMemoryRepository[T]) deleteMatching(filters []FilterFunc) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids, err := r.matchingIDs(filters)
+	if err != nil {
+		return nil, errors.Join(ErrFailedToDeleteMany, err)
+	}
+	for _, id := range ids {
+		r.remove(id)
+	}
+	return ids, nil
+}
+
+// FindManyByFilter returns the documents matching the filters, applying skip and limit the same way
+// as the MongoDB repository. If no documents match the filters, it returns an error with the ErrNotFound
+// error code, or an empty slice if the repository is created with WithEmptyResultNil.
+func (r *inMemoryRepository[T]) FindManyByFilter(ctx context.Context, skip int64, limit int64, filters ...FilterFunc) (_ []T, err error) {
+	ctx, op := r.startOperation(ctx, "FindManyByFilter")
+	defer func() { op.end(err) }()
+
+	limit, err = r.opts.limit(limit)
+	if err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	docs, err := r.find(ctx, filters)
+	if err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+	if skip >= int64(len(docs)) {
+		docs = nil
+	} else {
+		docs = docs[skip:]
+	}
+	if limit > 0 && limit < int64(len(docs)) {
+		docs = docs[:limit]
+	}
+	if len(docs) == 0 {
+		if r.opts.emptyResultNil{
+			return []T{}, nil
+		}
+		return nil, errors.Join(ErrFailedToFindManyByFilter, ErrNotFound)
+	}
+
+	results := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		result, err := decodeDoc[T](doc)
+		if err != nil {
+			return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+		results = append(results, result)
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// FindManyByFilterWithConfig works as FindManyByFilter: the FindConfig(s) tune the MongoDB cursor,
+// so they have no effect on the in-memory repository.
+func (r *inMemoryRepository[T]) FindManyByFilterWithConfig(ctx context.Context, skip, limit int64, config []FindConfig, filters ...FilterFunc) ([]T, error) {
+	return r.FindManyByFilter(ctx, skip, limit, filters...)
+}
+
+// CachedFindMany works as FindManyByFilter, since reading from memory needs no caching.
+func (r *inMemoryRepository[T]) CachedFindMany(ctx context.Context, ttl time.Duration, skip, limit int64, filters ...FilterFunc) ([]T, error) {
+	return r.FindManyByFilter(ctx, skip, limit, filters...)
+}
+
+// FindInto finds all documents matching the filters and decodes them into the caller-provided slice.
+// The slice is truncated first, and it's left empty if an error occurs.
+func (r *inMemoryRepository[T]) FindInto(ctx context.Context, dst *[]T, filters ...FilterFunc) (err error) {
+	ctx, op := r.startOperation(ctx, "FindInto")
+	defer func() { op.end(err) }()
+
+	results := (*dst)[:0]
+	defer func() {
+		if err != nil {
+			results = results[:0]
+		}
+		*dst = results
+	}()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	docs, err := r.find(ctx, filters)
+	if err != nil {
+		return errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+	for _, doc := range docs {
+		result, err := decodeDoc[T](doc)
+		if err != nil {
+			return errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+		results = append(results, result)
+	}
+	op.setDocumentCount(int64(len(results)))
+	return nil
+}
+
+// FindDuplicatesOf is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) FindDuplicatesOf(ctx context.Context, hashField string, model T, hashFn func(T) string) ([]T, error) {
+	return nil, notSupported("FindDuplicatesOf")
+}
+
+// Tail is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) Tail(ctx context.Context, fn func(T) error, filters ...FilterFunc) error {
+	return notSupported("Tail")
+}
+
+// Watch is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) Watch(ctx context.Context, fn func(ChangeEvent[T]) error, pipeline ...bson.D) error {
+	return notSupported("Watch")
+}
+
+// WatchFrom is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) WatchFrom(ctx context.Context, resumeToken bson.Raw, fn func(ChangeEvent[T]) error, pipeline ...bson.D) error {
+	return notSupported("WatchFrom")
+}
+
+// FindOneByFilter returns the first document matching the filters.
+// If no document is found, it returns an error with the ErrNotFound error code.
+func (r *inMemoryRepository[T]) FindOneByFilter(ctx context.Context, filters ...FilterFunc) (_ T, err error) {
+	ctx, op := r.startOperation(ctx, "FindOneByFilter")
+	defer func() { op.end(err) }()
+
+	var result T
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	docs, err := r.find(ctx, filters)
+	if err != nil {
+		return result, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
+	if len(docs) == 0 {
+		return result, errors.Join(ErrFailedToFindOneByFilter, ErrNotFound)
+	}
+	result, err = decodeDoc[T](docs[0])
+	if err != nil {
+		return result, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
+	return result, nil
+}
+
+// Exists reports whether any document matches the filters.
+func (r *inMemoryRepository[T]) Exists(ctx context.Context, filters ...FilterFunc) (bool, error) {
+	count, err := r.Count(ctx, filters...)
+	return count > 0, err
+}
+
+// WouldViolateUnique reports whether a document with the given field value exists among the documents
+// matching the filters.
+func (r *inMemoryRepository[T]) WouldViolateUnique(ctx context.Context, field string, value interface{}, filters ...FilterFunc) (bool, error) {
+	return r.Exists(ctx, append([]FilterFunc{Eq(field, value)}, filters...)...)
+}
+
+// Count returns the number of documents matching the filters.
+func (r *inMemoryRepository[T]) Count(ctx context.Context, filters ...FilterFunc) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "Count")
+	defer func() { op.end(err) }()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids, err := r.matchingIDs(filters)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
+	op.setDocumentCount(int64(len(ids)))
+	return int64(len(ids)), nil
+}
+
+// CountAffected returns the number of documents a write with the same filters would affect,
+// without modifying anything.
+func (r *inMemoryRepository[T]) CountAffected(ctx context.Context, filters ...FilterFunc) (int64, error) {
+	return r.Count(ctx, filters...)
+}
+
+// EstimatedCount returns the exact number of documents, which is cheap to get in memory.
+func (r *inMemoryRepository[T]) EstimatedCount(ctx context.Context) (_ int64, err error) {
+	_, op := r.startOperation(ctx, "EstimatedCount")
+	defer func() { op.end(err) }()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(len(r.docs)), nil
+}
+
+// ListIndexes is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) ListIndexes(ctx context.Context) ([]IndexInfo, error) {
+	return nil, notSupported("ListIndexes")
+}
+
+// DistinctWithCounts is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) DistinctWithCounts(ctx context.Context, field string, filters ...FilterFunc) ([]ValueCount, error) {
+	return nil, notSupported("DistinctWithCounts")
+}
+
+// MaxTime is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) MaxTime(ctx context.Context, field string, filters ...FilterFunc) (time.Time, error) {
+	return time.Time{}, notSupported("MaxTime")
+}
+
+// MinTime is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) MinTime(ctx context.Context, field string, filters ...FilterFunc) (time.Time, error) {
+	return time.Time{}, notSupported("MinTime")
+}
+
+// FindOneWithSlice is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) FindOneWithSlice(ctx context.Context, field string, n int, filters ...FilterFunc) (SlicedResult[T], error) {
+	return SlicedResult[T]{}, notSupported("FindOneWithSlice")
+}
+
+// Facet is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) Facet(ctx context.Context, groupBy string, filters ...FilterFunc) (map[string]int64, error) {
+	return nil, notSupported("Facet")
+}
+
+// FindManyWithSize is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) FindManyWithSize(ctx context.Context, filters ...FilterFunc) ([]SizedResult[T], error) {
+	return nil, notSupported("FindManyWithSize")
+}
+
+// BulkWrite is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) BulkWrite(ctx context.Context, ops []WriteOp, ordered bool) (BulkResult, error) {
+	return BulkResult{}, notSupported("BulkWrite")
+}
+
+// ValidateDocument checks the model with its Validator implementation, if any,
+// since the in-memory repository has no collection validator.
+func (r *inMemoryRepository[T]) ValidateDocument(ctx context.Context, model T) error {
+	return validate(&model)
+}
+
+// CountByTimeBucket is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) CountByTimeBucket(ctx context.Context, field, unit, timezone string, filters ...FilterFunc) ([]TimeBucket, error) {
+	return nil, notSupported("CountByTimeBucket")
+}
+
+// JoinStream is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) JoinStream(ctx context.Context, from, localField, foreignField, as string, fn func(bson.M) error, filters ...FilterFunc) error {
+	return notSupported("JoinStream")
+}
+
+// Explain is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) Explain(ctx context.Context, filters ...FilterFunc) (bson.M, error) {
+	return nil, notSupported("Explain")
+}
+
+// FindPageFaceted returns a page of the documents matching the filters along with the total number
+// of matching documents, the same way as FindManyByFilter and Count.
+func (r *inMemoryRepository[T]) FindPageFaceted(ctx context.Context, skip, limit int64, filters ...FilterFunc) ([]T, int64, error) {
+	total, err := r.Count(ctx, filters...)
+	if err != nil {
+		return nil, 0, errors.Join(ErrFailedToAggregate, err)
+	}
+	docs, err := r.FindManyByFilter(ctx, skip, limit, filters...)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, 0, errors.Join(ErrFailedToAggregate, err)
+	}
+	if docs == nil {
+		docs = []T{}
+	}
+	return docs, total, nil
+}
+
+// CountByPeriod is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) CountByPeriod(ctx context.Context, dateField string, granularity string, filters ...FilterFunc) ([]PeriodCount, error) {
+	return nil, notSupported("CountByPeriod")
+}
+
+// Aggregate is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) Aggregate(ctx context.Context, pipeline mongo.Pipeline) (*mongo.Cursor, error) {
+	return nil, notSupported("Aggregate")
+}
+
+// UpdateManyOps is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) UpdateManyOps(ctx context.Context, ops []UpdateFunc, filters ...FilterFunc) (int64, error) {
+	return 0, notSupported("UpdateManyOps")
+}
+
+// UpsertMany is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) UpsertMany(ctx context.Context, keyField string, models []T) (BulkResult, error) {
+	return BulkResult{}, notSupported("UpsertMany")
+}
+
+// Stats is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) Stats(ctx context.Context) (CollStats, error) {
+	return CollStats{}, notSupported("Stats")
+}
+
+// Sum is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) Sum(ctx context.Context, groupBy, sumField string, filters ...FilterFunc) (map[string]float64, error) {
+	return nil, notSupported("Sum")
+}
+
+// Avg is not supported by the in-memory repository.
+func (r *inMemoryRepository[T]) Avg(ctx context.Context, groupBy, avgField string, filters ...FilterFunc) (map[string]float64, error) {
+	return nil, notSupported("Avg")
+}
+
+// store replaces the document with the updated one and returns the number of modified documents,
+// which is zero if the update didn't change anything. The caller must hold the write lock.
+func (r *inMemoryRepository[T]) store(id string, doc, updated bson.D) (int64, error) {
+	before, err := bson.Marshal(doc)
+	if err != nil {
+		return 0, err
+	}
+	after, err := bson.Marshal(updated)
+	if err != nil {
+		return 0, err
+	}
+	if bytes.Equal(before, after) {
+		return 0, nil
+	}
+	r.docs[id] = updated
+	return 1, nil
+}
+
+// remove deletes the document with the given ID. The caller must hold the write lock.
+func (r *inMemoryRepository[T]) remove(id string) {
+	delete(r.docs, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// matchingIDs returns the IDs of the documents matching the filters in the insertion order.
+// The caller must hold the lock.
+func (r *inMemoryRepository[T]) matchingIDs(filters []FilterFunc) ([]string, error) {
+	filter, err := buildFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, id := range r.order {
+		matched, err := matchDoc(r.docs[id], filter)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// find returns the documents matching the filters, sorted by the sort carried by the context, if any.
+// The caller must hold the lock.
+func (r *inMemoryRepository[T]) find(ctx context.Context, filters []FilterFunc) ([]bson.D, error) {
+	ids, err := r.matchingIDs(filters)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]bson.D, len(ids))
+	for i, id := range ids {
+		docs[i] = r.docs[id]
+	}
+	if sortSpec := queryOptionsFromContext(ctx).sort; len(sortSpec) > 0 {
+		sort.SliceStable(docs, func(i, j int) bool {
+			for _, e := range sortSpec {
+				a, _ := lookupField(docs[i], e.Key)
+				b, _ := lookupField(docs[j], e.Key)
+				c, _ := compareValues(a, b)
+				if c == 0 {
+					continue
+				}
+				if direction, _ := toFloat(e.Value); direction < 0 {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+	return docs, nil
+}
+
+// decodeDoc decodes the stored document into the model.
+func decodeDoc[T any](doc bson.D) (T, error) {
+	var result T
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return result, err
+	}
+	err = bson.Unmarshal(data, &result)
+	return result, err
+}
+
+// cloneDoc returns a deep copy of the document, so updates never change the stored documents in place.
+func cloneDoc(doc bson.D) bson.D {
+	clone := make(bson.D, len(doc))
+	for i, e := range doc {
+		if nested, ok := e.Value.(bson.D); ok {
+			e.Value = cloneDoc(nested)
+		}
+		clone[i] = e
+	}
+	return clone
+}
+
+// setFields sets the fields of a copy of the document the same way as $set, in the order of the keys,
+// since MongoDB adds new fields in lexicographic order as well.
+func setFields(doc bson.D, fields map[string]interface{}) (bson.D, error) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	updated := cloneDoc(doc)
+	for _, key := range keys {
+		value, err := normalizeValue(fields[key])
+		if err != nil {
+			return nil, err
+		}
+		if updated, err = setField(updated, key, value); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// setField sets the field given in dot notation, creating the missing embedded documents along the path.
+func setField(doc bson.D, path string, value interface{}) (bson.D, error) {
+	key, rest, nested := strings.Cut(path, ".")
+	if key == "_id" {
+		return nil, errors.New("the _id field is immutable")
+	}
+	for i, e := range doc {
+		if e.Key != key {
+			continue
+		}
+		if !nested {
+			doc[i].Value = value
+			return doc, nil
+		}
+		sub, ok := e.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("can't set %q: %q is not an embedded document", path, key)
+		}
+		sub, err := setField(sub, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		doc[i].Value = sub
+		return doc, nil
+	}
+	if nested {
+		sub, err := setField(bson.D{}, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		value = sub
+	}
+	return append(doc, bson.E{Key: key, Value: value}), nil
+}
+
+// normalizeValue converts the value to the type it has once stored and read back, e.g. a time.Time
+// to a primitive.DateTime and a struct to a bson.D, so it can be compared with the stored values.
+func normalizeValue(value interface{}) (interface{}, error) {
+	doc, err := toBsonD(bson.D{{Key: "v", Value: value}})
+	if err != nil {
+		return nil, err
+	}
+	return doc[0].Value, nil
+}
+
+// buildFilter builds the filter document of the filters, normalized the same way as the stored documents.
+func buildFilter(filters []FilterFunc) (bson.D, error) {
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	return toBsonD(filter)
+}
+
+// matchDoc reports whether the document matches the normalized filter.
+// It returns an error with the ErrUnsupportedFilter error code if the filter uses an unsupported operator.
+func matchDoc(doc bson.D, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		var matched bool
+		var err error
+		switch e.Key {
+		case "$and", "$or", "$nor":
+			matched, err = matchLogical(doc, e.Key, e.Value)
+		default:
+			if strings.HasPrefix(e.Key, "$") {
+				return false, errors.Join(ErrUnsupportedFilter, fmt.Errorf("operator %s", e.Key))
+			}
+			value, exists := lookupField(doc, e.Key)
+			matched, err = matchCondition(value, exists, e.Value)
+		}
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchLogical evaluates the $and, $or and $nor operators with the given array of filters.
+func matchLogical(doc bson.D, operator string, value interface{}) (bool, error) {
+	filters, ok := value.(bson.A)
+	if !ok {
+		return false, errors.Join(ErrUnsupportedFilter, fmt.Errorf("%s requires an array", operator))
+	}
+	for _, f := range filters {
+		sub, ok := f.(bson.D)
+		if !ok {
+			return false, errors.Join(ErrUnsupportedFilter, fmt.Errorf("%s requires an array of documents", operator))
+		}
+		matched, err := matchDoc(doc, sub)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case operator == "$and" && !matched:
+			return false, nil
+		case operator == "$or" && matched:
+			return true, nil
+		case operator == "$nor" && matched:
+			return false, nil
+		}
+	}
+	return operator != "$or", nil
+}
+
+// matchCondition reports whether the field value matches the condition, which is either a value
+// to be equal to or a document of query operators, e.g. {$gt: 5, $lt: 10}.
+func matchCondition(value interface{}, exists bool, cond interface{}) (bool, error) {
+	if _, ok := cond.(primitive.Regex); ok {
+		return false, errors.Join(ErrUnsupportedFilter, errors.New("regular expressions"))
+	}
+	ops, ok := cond.(bson.D)
+	if !ok || len(ops) == 0 || !strings.HasPrefix(ops[0].Key, "$") {
+		return matchEq(value, cond), nil
+	}
+	for _, op := range ops {
+		var matched bool
+		switch op.Key {
+		case "$eq":
+			matched = matchEq(value, op.Value)
+		case "$ne":
+			matched = !matchEq(value, op.Value)
+		case "$gt", "$gte", "$lt", "$lte":
+			matched = matchAny(value, func(v interface{}) bool {
+				c, ok := compareValues(v, op.Value)
+				if !ok {
+					return false
+				}
+				switch op.Key {
+				case "$gt":
+					return c > 0
+				case "$gte":
+					return c >= 0
+				case "$lt":
+					return c < 0
+				default:
+					return c <= 0
+				}
+			})
+		case "$in", "$nin":
+			values, ok := op.Value.(bson.A)
+			if !ok {
+				return false, errors.Join(ErrUnsupportedFilter, fmt.Errorf("%s requires an array", op.Key))
+			}
+			for _, v := range values {
+				if matchEq(value, v) {
+					matched = true
+					break
+				}
+			}
+			if op.Key == "$nin" {
+				matched = !matched
+			}
+		case "$exists":
+			want, _ := op.Value.(bool)
+			matched = exists == want
+		case "$not":
+			notMatched, err := matchCondition(value, exists, op.Value)
+			if err != nil {
+				return false, err
+			}
+			matched = !notMatched
+		default:
+			return false, errors.Join(ErrUnsupportedFilter, fmt.Errorf("operator %s", op.Key))
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchEq reports whether the value equals the target, or, if the value is an array,
+// whether the array itself or any of its elements equals the target, the same way as MongoDB.
+// A null target matches missing fields as well.
+func matchEq(value, target interface{}) bool {
+	if sameValue(value, target) {
+		return true
+	}
+	if arr, ok := value.(bson.A); ok {
+		for _, v := range arr {
+			if sameValue(v, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAny reports whether the value, or any of its elements if it's an array, satisfies the predicate.
+func matchAny(value interface{}, pred func(interface{}) bool) bool {
+	if arr, ok := value.(bson.A); ok {
+		for _, v := range arr {
+			if pred(v) {
+				return true
+			}
+		}
+		return false
+	}
+	return pred(value)
+}
+
+// sameValue reports whether both normalized values are equal. Numbers of different types are equal
+// if they have the same value, and documents and arrays are equal if they have the same BSON encoding.
+func sameValue(a, b interface{}) bool {
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+	ta, da, errA := bson.MarshalValue(a)
+	tb, db, errB := bson.MarshalValue(b)
+	return errA == nil && errB == nil && ta == tb && bytes.Equal(da, db)
+}
+
+// compareValues compares two normalized scalar values of the same kind, e.g. two numbers or two strings.
+// It returns -1, 0 or 1 and true, or false if the values can't be compared, which is also
+// the case for values of different kinds, since MongoDB range operators only match values of the same type.
+// Missing and null values are equal to each other.
+func compareValues(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return 0, true
+		}
+		return 0, false
+	}
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(fa, fb), true
+	}
+	switch va := a.(type) {
+	case string:
+		vb, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(va, vb), true
+	case bool:
+		vb, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if va == vb {
+			return 0, true
+		}
+		if vb {
+			return -1, true
+		}
+		return 1, true
+	case primitive.DateTime:
+		vb, ok := b.(primitive.DateTime)
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(va, vb), true
+	case primitive.ObjectID:
+		vb, ok := b.(primitive.ObjectID)
+		if !ok {
+			return 0, false
+		}
+		return bytes.Compare(va[:], vb[:]), true
+	}
+	return 0, false
+}
+
+// compareOrdered compares two ordered values.
+func compareOrdered[V float64 | primitive.DateTime](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// toFloat converts a normalized numeric value to float64 and reports whether the value is numeric.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
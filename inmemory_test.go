@@ -0,0 +1,188 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type suiteAddress struct {
+	City string `bson:"city"`
+}
+
+type suiteUser struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"`
+	Name    string             `bson:"name"`
+	Age     int                `bson:"age"`
+	Tags    []string           `bson:"tags"`
+	Address suiteAddress       `bson:"address"`
+}
+
+func TestInMemoryRepository(t *testing.T) {
+	testRepositorySuite(t, mongorepository.NewInMemoryRepository[suiteUser]())
+
+	t.Run("UnsupportedFilter", func(t *testing.T) {
+		repo := mongorepository.NewInMemoryRepository[suiteUser]()
+		_, err := repo.Create(context.Background(), suiteUser{Name: "John"})
+		require.NoError(t, err)
+
+		_, err = repo.FindManyByFilter(context.Background(), 0, 0, mongorepository.Regex("name", "^J", ""))
+		require.ErrorIs(t, err, mongorepository.ErrUnsupportedFilter)
+		_, err = repo.Count(context.Background(), mongorepository.Size("tags", 1))
+		require.ErrorIs(t, err, mongorepository.ErrUnsupportedFilter)
+	})
+
+	t.Run("NotSupported", func(t *testing.T) {
+		repo := mongorepository.NewInMemoryRepository[suiteUser]()
+		_, err := repo.Facet(context.Background(), "name")
+		require.ErrorIs(t, err, mongorepository.ErrNotSupported)
+	})
+}
+
+func TestRepositorySuite(t *testing.T) {
+	db := setupMongoDB(t)
+	testRepositorySuite(t, mongorepository.NewMongoRepository[suiteUser](db, "suite_users"))
+}
+
+// testRepositorySuite runs the same checks against any Repository implementation,
+// so the in-memory repository is verified to behave like the MongoDB one.
+func testRepositorySuite(t *testing.T, repo mongorepository.Repository[suiteUser]) {
+	ctx := context.Background()
+
+	users := []suiteUser{
+		{Name: "John", Age: 30, Tags: []string{"admin", "dev"}, Address: suiteAddress{City: "Berlin"}},
+		{Name: "Jane", Age: 25, Tags: []string{"dev"}, Address: suiteAddress{City: "Paris"}},
+		{Name: "Alex", Age: 40, Address: suiteAddress{City: "Berlin"}},
+	}
+	ids := make([]string, len(users))
+	for i, user := range users {
+		id, err := repo.Create(ctx, user)
+		require.NoError(t, err)
+		ids[i] = id
+	}
+
+	names := func(filters ...mongorepository.FilterFunc) []string {
+		found, err := repo.FindManyByFilter(ctx, 0, 0, filters...)
+		if err != nil {
+			require.ErrorIs(t, err, mongorepository.ErrNotFound)
+			return nil
+		}
+		result := make([]string, 0, len(found))
+		for _, u := range found {
+			result = append(result, u.Name)
+		}
+		return result
+	}
+
+	t.Run("FindByID", func(t *testing.T) {
+		user, err := repo.FindByID(ctx, ids[0])
+		require.NoError(t, err)
+		assert.Equal(t, "John", user.Name)
+		assert.Equal(t, ids[0], user.ID.Hex())
+
+		_, err = repo.FindByID(ctx, primitive.NewObjectID().Hex())
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+
+		_, err = repo.FindByID(ctx, "invalid")
+		require.ErrorIs(t, err, mongorepository.ErrInvalidDocumentID)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		assert.Equal(t, []string{"Jane"}, names(mongorepository.Eq("name", "Jane")))
+		assert.Equal(t, []string{"John", "Alex"}, names(mongorepository.Gt("age", 25)))
+		assert.Equal(t, []string{"Jane"}, names(mongorepository.Lt("age", 30)))
+		assert.Equal(t, []string{"John", "Jane"}, names(mongorepository.Gte("age", 25), mongorepository.Lte("age", 30)))
+		assert.Equal(t, []string{"John", "Alex"}, names(mongorepository.In("name", []string{"John", "Alex", "Bob"})))
+		assert.Equal(t, []string{"John", "Jane"}, names(mongorepository.Eq("tags", "dev")))
+		assert.Equal(t, []string{"John", "Alex"}, names(mongorepository.Eq("address.city", "Berlin")))
+		assert.Equal(t, []string{"Jane", "Alex"}, names(mongorepository.Ne("name", "John")))
+		assert.Equal(t, []string{"Alex"}, names(mongorepository.IsNull("tags")))
+		assert.Equal(t, []string{"Jane", "Alex"}, names(mongorepository.Not(mongorepository.Eq("tags", "admin"))))
+		assert.Equal(t, []string{"John"}, names(mongorepository.And(
+			mongorepository.Eq("address.city", "Berlin"),
+			mongorepository.Lt("age", 35),
+		)))
+		assert.Equal(t, []string{"Jane", "Alex"}, names(mongorepository.Or(
+			mongorepository.Eq("name", "Jane"),
+			mongorepository.Gt("age", 35),
+		)))
+		assert.Empty(t, names(mongorepository.Eq("name", "Bob")))
+	})
+
+	t.Run("SkipLimitSort", func(t *testing.T) {
+		sorted := mongorepository.WithQueryOptions(ctx, mongorepository.WithSort(bson.D{{Key: "age", Value: -1}}))
+		found, err := repo.FindManyByFilter(sorted, 1, 1)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, "John", found[0].Name)
+
+		user, err := repo.FindOneByFilter(sorted)
+		require.NoError(t, err)
+		assert.Equal(t, "Alex", user.Name)
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		count, err := repo.Count(ctx, mongorepository.Eq("address.city", "Berlin"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		exists, err := repo.Exists(ctx, mongorepository.Eq("name", "Bob"))
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		user, err := repo.FindByID(ctx, ids[1])
+		require.NoError(t, err)
+		user.Age = 26
+
+		modified, err := repo.Update(ctx, ids[1], user)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), modified)
+
+		// Updating with the same values doesn't modify the document
+		modified, err = repo.Update(ctx, ids[1], user)
+		require.NoError(t, err)
+		assert.Zero(t, modified)
+
+		modified, err = repo.UpdateFields(ctx, ids[1], map[string]interface{}{"address.city": "Lyon"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), modified)
+
+		user, err = repo.FindByID(ctx, ids[1])
+		require.NoError(t, err)
+		assert.Equal(t, 26, user.Age)
+		assert.Equal(t, "Lyon", user.Address.City)
+
+		_, err = repo.Update(ctx, primitive.NewObjectID().Hex(), user)
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+	})
+
+	t.Run("UpdateMany", func(t *testing.T) {
+		modified, err := repo.UpdateMany(ctx, map[string]interface{}{"address.city": "Munich"}, mongorepository.Eq("address.city", "Berlin"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), modified)
+		assert.Equal(t, []string{"John", "Alex"}, names(mongorepository.Eq("address.city", "Munich")))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		deleted, err := repo.Delete(ctx, ids[2])
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		_, err = repo.Delete(ctx, ids[2])
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+
+		deleted, err = repo.DeleteMany(ctx, mongorepository.Eq("tags", "dev"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+
+		_, err = repo.FindManyByFilter(ctx, 0, 0)
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+	})
+}
@@ -0,0 +1,15 @@
+package mongorepository
+
+// Map converts each item of the slice with the given function and returns the resulting slice,
+// e.g. to turn the documents found by the repository into API DTOs.
+// It returns nil if the items slice is nil.
+func Map[T, R any](items []T, fn func(T) R) []R {
+	if items == nil {
+		return nil
+	}
+	results := make([]R, len(items))
+	for i, item := range items {
+		results[i] = fn(item)
+	}
+	return results
+}
@@ -0,0 +1,26 @@
+package mongorepository_test
+
+import (
+	"testing"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMap(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	users := []User{
+		{ID: primitive.NewObjectID(), Name: "John"},
+		{ID: primitive.NewObjectID(), Name: "Jane"},
+	}
+
+	names := mongorepository.Map(users, func(u User) string { return u.Name })
+	assert.Equal(t, []string{"John", "Jane"}, names)
+
+	assert.Nil(t, mongorepository.Map[User, string](nil, func(u User) string { return u.Name }))
+}
@@ -0,0 +1,107 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CopyField copies the value of the "from" field into the "to" field for all documents matching the filters.
+// It uses an update with an aggregation pipeline, so it requires MongoDB 4.2 or later.
+// Documents missing the "from" field are left without the "to" field.
+// It returns the number of documents modified and an error if any.
+func (r *mongoRepository[T]) CopyField(ctx context.Context, from, to string, filters ...FilterFunc) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "CopyField")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	// Pipeline update: the "$" prefix references the value of the source field
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{to: "$" + from}}},
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, pipeline)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdateMany, err)
+	}
+	op.setDocumentCount(result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
+
+// RenameField renames the "from" field to "to" in all documents matching the filters with $rename,
+// e.g. for schema migrations. Only the documents having the "from" field are updated.
+// If a document already has the "to" field, its value is overwritten. Dotted paths rename fields
+// of embedded documents, but fields inside arrays can't be renamed.
+// It returns the number of documents modified and an error if any.
+func (r *mongoRepository[T]) RenameField(ctx context.Context, from, to string, filters ...FilterFunc) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "RenameField")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	filter = append(filter, bson.E{Key: from, Value: bson.M{"$exists": true}})
+
+	result, err := r.collection.UpdateMany(ctx, filter, bson.M{"$rename": bson.M{from: to}})
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdateMany, err)
+	}
+	op.setDocumentCount(result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
+
+// StringNormalizeMode defines how NormalizeStringField transforms the string values.
+type StringNormalizeMode int
+
+// Predefined string normalization modes
+const (
+	NormalizeLower StringNormalizeMode = iota // Convert to lower case, e.g. for emails
+	NormalizeUpper                            // Convert to upper case, e.g. for country codes
+	NormalizeTrim                             // Remove leading and trailing whitespaces
+)
+
+// NormalizeStringField transforms the string values of the field in all documents matching the filters
+// according to the given mode in a single operation, e.g. to lowercase all existing emails.
+// Documents where the field is missing or is not a string are left untouched.
+// It uses an update with an aggregation pipeline, so it requires MongoDB 4.2 or later.
+// It returns the number of documents modified and an error if any.
+func (r *mongoRepository[T]) NormalizeStringField(ctx context.Context, field string, mode StringNormalizeMode, filters ...FilterFunc) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "NormalizeStringField")
+	defer func() { op.end(err) }()
+
+	var expr bson.M
+	switch mode {
+	case NormalizeLower:
+		expr = bson.M{"$toLower": "$" + field}
+	case NormalizeUpper:
+		expr = bson.M{"$toUpper": "$" + field}
+	case NormalizeTrim:
+		expr = bson.M{"$trim": bson.M{"input": "$" + field}}
+	default:
+		return 0, errors.Join(ErrFailedToUpdateMany, ErrInvalidNormalizeMode)
+	}
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	filter = append(filter, bson.E{Key: field, Value: bson.M{"$type": "string"}})
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{field: expr}}},
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, pipeline)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdateMany, err)
+	}
+	op.setDocumentCount(result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,107 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMigrations(t *testing.T) {
+	type User struct {
+		ID          primitive.ObjectID `bson:"_id,omitempty"`
+		Name        string             `bson:"name"`
+		DisplayName string             `bson:"display_name,omitempty"`
+		Email       string             `bson:"email,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	names := []string{"John", "Jane", "Alex"}
+	for _, name := range names {
+		_, err := repo.Create(context.Background(), User{Name: name})
+		require.NoError(t, err)
+	}
+
+	t.Run("CopyField", func(t *testing.T) {
+		modified, err := repo.CopyField(context.Background(), "name", "display_name")
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(names)), modified)
+
+		users, err := repo.FindManyByFilter(context.Background(), 0, 0)
+		require.NoError(t, err)
+		require.Len(t, users, len(names))
+		for _, user := range users {
+			assert.Equal(t, user.Name, user.DisplayName)
+		}
+	})
+
+	t.Run("NormalizeStringField", func(t *testing.T) {
+		emails := []string{"John@Example.com", "  JANE@example.COM ", "alex@example.com"}
+		for _, email := range emails {
+			_, err := repo.Create(context.Background(), User{Name: "Email", Email: email})
+			require.NoError(t, err)
+		}
+
+		modified, err := repo.NormalizeStringField(context.Background(), "email", mongorepository.NormalizeTrim)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), modified)
+
+		modified, err = repo.NormalizeStringField(
+			context.Background(), "email", mongorepository.NormalizeLower,
+			mongorepository.Eq("name", "Email"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), modified)
+
+		users, err := repo.FindManyByFilter(context.Background(), 0, 0, mongorepository.Eq("name", "Email"))
+		require.NoError(t, err)
+		require.Len(t, users, 3)
+		assert.Equal(t, "john@example.com", users[0].Email)
+		assert.Equal(t, "jane@example.com", users[1].Email)
+		assert.Equal(t, "alex@example.com", users[2].Email)
+	})
+}
+
+func TestRenameField(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	// Documents stored with the old schema
+	_, err := db.Collection("users").InsertMany(context.Background(), []interface{}{
+		bson.M{"fullname": "John Doe"},
+		bson.M{"fullname": "Jane Doe"},
+		bson.M{"name": "Alex"},
+	})
+	require.NoError(t, err)
+
+	modified, err := repo.RenameField(context.Background(), "fullname", "name")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), modified)
+
+	users, err := repo.FindManyByFilter(context.Background(), 0, 0)
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+	assert.Equal(t, "John Doe", users[0].Name)
+	assert.Equal(t, "Jane Doe", users[1].Name)
+	assert.Equal(t, "Alex", users[2].Name)
+
+	// The old key is gone
+	count, err := repo.Count(context.Background(), mongorepository.Exists("fullname", true))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	var raw bson.M
+	require.NoError(t, db.Collection("users").FindOne(context.Background(), bson.M{"name": "John Doe"}).Decode(&raw))
+	assert.NotContains(t, raw, "fullname")
+}
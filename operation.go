@@ -0,0 +1,78 @@
+package mongorepository
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// operation tracks a single repository operation for the query hook and tracing.
+type operation struct {
+	name   string
+	start  time.Time
+	opts   *repositoryOptions
+	span   trace.Span
+	cancel context.CancelFunc
+}
+
+// startOperation is called at the beginning of every repository operation.
+// It returns the context the operation must use and the operation to be ended with the operation error.
+// If the context has no deadline, the default timeout set by WithDefaultTimeout is applied to it.
+func (r *mongoRepository[T]) startOperation(ctx context.Context, name string) (context.Context, *operation) {
+	return r.beginOperation(ctx, name, true)
+}
+
+// startStreamingOperation works as startOperation, but for the operations iterating over a cursor
+// until it's exhausted or the caller stops them, e.g. Tail, Watch and JoinStream,
+// so the default timeout set by WithDefaultTimeout isn't applied to them.
+func (r *mongoRepository[T]) startStreamingOperation(ctx context.Context, name string) (context.Context, *operation) {
+	return r.beginOperation(ctx, name, false)
+}
+
+// beginOperation starts the operation, applying the default timeout to the context if withTimeout is true.
+func (r *mongoRepository[T]) beginOperation(ctx context.Context, name string, withTimeout bool) (context.Context, *operation) {
+	op := &operation{name: name, start: time.Now(), opts: &r.opts}
+	if r.opts.defaultTimeout > 0 && withTimeout {
+		if _, ok := ctx.Deadline(); !ok {
+			ctx, op.cancel = context.WithTimeout(ctx, r.opts.defaultTimeout)
+		}
+	}
+	if r.opts.tracer != nil {
+		ctx, op.span = r.opts.tracer.Start(ctx, "mongo."+name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "mongodb"),
+				attribute.String("db.operation", name),
+				attribute.String("db.mongodb.collection", r.collection.Name()),
+			),
+		)
+	}
+	return ctx, op
+}
+
+// setDocumentCount records the number of documents returned or affected by the operation.
+func (op *operation) setDocumentCount(n int64) {
+	if op.span != nil {
+		op.span.SetAttributes(attribute.Int64("db.mongodb.document_count", n))
+	}
+}
+
+// end finishes the operation with the given error, if any.
+func (op *operation) end(err error) {
+	if op.cancel != nil {
+		op.cancel()
+	}
+	if op.opts.queryHook != nil {
+		op.opts.queryHook(op.name, time.Since(op.start), err)
+	}
+	if op.span != nil {
+		if err != nil {
+			op.span.RecordError(err)
+			op.span.SetStatus(codes.Error, err.Error())
+		}
+		op.span.End()
+	}
+}
@@ -0,0 +1,153 @@
+package mongorepository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryHook is a function called after every repository operation
+// with the operation name, the elapsed time and the operation error, if any.
+type QueryHook func(op string, dur time.Duration, err error)
+
+// Option configures the repository created by NewMongoRepository.
+type Option func(*repositoryOptions)
+
+// repositoryOptions holds the repository configuration set by the Option(s).
+type repositoryOptions struct {
+	queryHook      QueryHook
+	tracer         trace.Tracer
+	retryAttempts  int
+	retryBackoff   time.Duration
+	concurrency    int
+	defaultTimeout time.Duration
+	emptyResultNil bool
+	defaultLimit   int64
+	maxLimit       int64
+	failOnMaxLimit bool
+}
+
+// WithQueryHook sets a hook called after every repository operation.
+// It can be used to plug in slow-query logging or metrics without wrapping each method.
+// It can be passed multiple times, e.g. for logging and metrics; the hooks are called in the order they are set.
+func WithQueryHook(hook QueryHook) Option {
+	return func(opts *repositoryOptions) {
+		if prev := opts.queryHook; prev != nil {
+			opts.queryHook = func(op string, dur time.Duration, err error) {
+				prev(op, dur, err)
+				hook(op, dur, err)
+			}
+			return
+		}
+		opts.queryHook = hook
+	}
+}
+
+// WithTracer enables OpenTelemetry tracing of the repository operations.
+// Every operation starts a span named "mongo.<operation>" as a child of the span in the incoming context,
+// with the collection name and the number of returned or affected documents as attributes.
+// Operation errors are recorded on the span.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(opts *repositoryOptions) {
+		opts.tracer = tracer
+	}
+}
+
+// WithRetry enables automatic retries of read operations failed with transient errors,
+// i.e. timeouts and network errors. The operation is attempted up to maxAttempts times in total,
+// waiting for backoff before the first retry and doubling the delay after every attempt.
+// Write operations are never retried by the repository, since they are not idempotent in general;
+// use the driver's retryable writes for them instead.
+func WithRetry(maxAttempts int, backoff time.Duration) Option {
+	return func(opts *repositoryOptions) {
+		opts.retryAttempts = maxAttempts
+		opts.retryBackoff = backoff
+	}
+}
+
+// WithConcurrency sets the maximum number of queries FindByIDs issues in parallel
+// when the ids are split into chunks, to cut the latency of very large ID sets.
+// By default, the chunks are fetched sequentially.
+func WithConcurrency(n int) Option {
+	return func(opts *repositoryOptions) {
+		opts.concurrency = n
+	}
+}
+
+// WithDefaultTimeout sets the timeout applied to every repository operation called with a context without a deadline,
+// so that a slow operation can't hang forever. Contexts with a deadline are used as is, and the cancellation
+// of the incoming context is always propagated. The streaming operations, i.e. Tail, Watch, JoinStream
+// and SearchIterate, iterate over a cursor until the caller stops them, so the default timeout doesn't apply to them.
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(opts *repositoryOptions) {
+		opts.defaultTimeout = d
+	}
+}
+
+// WithEmptyResultNil makes FindManyByFilter, FindByIDs and Search return an empty slice and no error
+// when no documents match, which suits list endpoints better than an error.
+// By default, they return an error with the ErrNotFound error code in this case.
+func WithEmptyResultNil() Option {
+	return func(opts *repositoryOptions) {
+		opts.emptyResultNil = true
+	}
+}
+
+// WithEmptyResultOK is an alias of WithEmptyResultNil, named after the empty slice it returns.
+func WithEmptyResultOK() Option {
+	return WithEmptyResultNil()
+}
+
+// NoLimit can be passed as the limit to FindManyByFilter, Search and FindPageFaceted to return
+// all matching documents, regardless of the default limit.
+const NoLimit int64 = -1
+
+// defaultFindLimit is the limit applied when the caller passes zero and WithDefaultLimit is not set.
+const defaultFindLimit int64 = 10
+
+// WithDefaultLimit sets the limit FindManyByFilter, Search and FindPageFaceted apply when called with zero limit,
+// 10 by default. Pass NoLimit to return all matching documents by default.
+// An explicit NoLimit in a call always returns all matching documents.
+func WithDefaultLimit(n int64) Option {
+	return func(opts *repositoryOptions) {
+		opts.defaultLimit = n
+	}
+}
+
+// WithMaxLimit caps the limit FindManyByFilter, Search and FindPageFaceted apply, to protect the server
+// from clients requesting too many documents at once. NoLimit and the default limit are capped as well.
+// If failOnExceed is false, a requested limit exceeding the max is clamped silently;
+// otherwise, the call fails with an error with the ErrLimitExceeded error code.
+func WithMaxLimit(n int64, failOnExceed bool) Option {
+	return func(opts *repositoryOptions) {
+		opts.maxLimit = n
+		opts.failOnMaxLimit = failOnExceed
+	}
+}
+
+// limit resolves the limit requested by the caller: zero is replaced with the default limit,
+// and NoLimit (or any negative value) with zero, which means no limit for MongoDB.
+// The limit is capped by the max limit set by WithMaxLimit, if any.
+// It returns an error with the ErrLimitExceeded error code if the requested limit exceeds the max one
+// and the repository is configured to fail in this case.
+func (o *repositoryOptions) limit(limit int64) (int64, error) {
+	requested := limit != 0
+	if limit == 0 {
+		limit = defaultFindLimit
+		if o.defaultLimit != 0 {
+			limit = o.defaultLimit
+		}
+	}
+	if o.maxLimit > 0 && (limit < 0 || limit > o.maxLimit) {
+		if requested && o.failOnMaxLimit {
+			return 0, errors.Join(ErrLimitExceeded, fmt.Errorf("requested limit %d, max %d", limit, o.maxLimit))
+		}
+		return o.maxLimit, nil
+	}
+	if limit < 0 {
+		return 0, nil
+	}
+	return limit, nil
+}
@@ -0,0 +1,45 @@
+package mongorepository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryOptionsLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		requested int64
+		want      int64
+		wantErr   bool
+	}{
+		{"Default", nil, 0, 10, false},
+		{"Requested", nil, 50, 50, false},
+		{"NoLimit", nil, NoLimit, 0, false},
+		{"CustomDefault", []Option{WithDefaultLimit(20)}, 0, 20, false},
+		{"UnboundedDefault", []Option{WithDefaultLimit(NoLimit)}, 0, 0, false},
+		{"WithinMax", []Option{WithMaxLimit(100, false)}, 50, 50, false},
+		{"ClampedToMax", []Option{WithMaxLimit(100, false)}, 1000, 100, false},
+		{"NoLimitClampedToMax", []Option{WithMaxLimit(100, false)}, NoLimit, 100, false},
+		{"DefaultClampedToMax", []Option{WithDefaultLimit(200), WithMaxLimit(100, true)}, 0, 100, false},
+		{"ExceedsMax", []Option{WithMaxLimit(100, true)}, 1000, 0, true},
+		{"NoLimitExceedsMax", []Option{WithMaxLimit(100, true)}, NoLimit, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts repositoryOptions
+			for _, opt := range tt.opts {
+				opt(&opts)
+			}
+			got, err := opts.limit(tt.requested)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrLimitExceeded)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
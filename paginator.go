@@ -0,0 +1,154 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Paginator iterates over the documents matching the filters page by page.
+// It uses keyset pagination: each page starts right after the last document of the previous page,
+// so the cost of a page doesn't grow with its position as it does with skip.
+// The sort fields should be present in all documents and should be indexed for efficiency.
+type Paginator[T any] struct {
+	repo    *mongoRepository[T]
+	perPage int64
+	sort    bson.D
+	filters []FilterFunc
+	last    bson.Raw
+	hasNext bool
+}
+
+// NewPaginator creates a new instance of the Paginator[T] struct.
+// It takes the repository, the number of documents per page, the sort order and optional filters.
+// The sort order is a list of fields with 1 for ascending and -1 for descending order.
+// The _id field is appended to the sort order as a tiebreaker if it's not there,
+// so that every document appears exactly once.
+func NewPaginator[T any](repo *mongoRepository[T], perPage int64, sort bson.D, filters ...FilterFunc) *Paginator[T] {
+	if perPage <= 0 {
+		perPage = 10
+	}
+	keys := make(bson.D, 0, len(sort)+1)
+	hasID := false
+	for _, e := range sort {
+		if e.Key == "_id" {
+			hasID = true
+		}
+		keys = append(keys, e)
+	}
+	if !hasID {
+		keys = append(keys, bson.E{Key: "_id", Value: 1})
+	}
+	return &Paginator[T]{
+		repo:    repo,
+		perPage: perPage,
+		sort:    keys,
+		filters: filters,
+		hasNext: true,
+	}
+}
+
+// HasNext reports whether there are more pages to fetch.
+func (p *Paginator[T]) HasNext() bool {
+	return p.hasNext
+}
+
+// Next fetches the next page of documents.
+// It returns an empty slice without an error if there are no more pages.
+func (p *Paginator[T]) Next(ctx context.Context) (_ []T, err error) {
+	ctx, op := p.repo.startOperation(ctx, "Paginate")
+	defer func() { op.end(err) }()
+
+	if !p.hasNext {
+		return []T{}, nil
+	}
+
+	filter := bson.D{}
+	for _, f := range p.filters {
+		filter = f(filter)
+	}
+	if p.last != nil {
+		after, err := p.afterLastFilter()
+		if err != nil {
+			return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+		filter = bson.D{{Key: "$and", Value: bson.A{filter, after}}}
+	}
+
+	// Fetch one extra document to know whether there is a next page
+	findOptions := options.Find().SetSort(p.sort).SetLimit(p.perPage + 1)
+	cursor, err := p.repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+	defer cursor.Close(ctx)
+
+	results := make([]T, 0, p.perPage)
+	var last bson.Raw
+	for int64(len(results)) < p.perPage && cursor.Next(ctx) {
+		var element T
+		if err := cursor.Decode(&element); err != nil {
+			return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+		results = append(results, element)
+		last = cursor.Current
+	}
+	// The cursor reuses its buffer, so the last document must be copied before moving on
+	if last != nil {
+		p.last = append(bson.Raw(nil), last...)
+	}
+	p.hasNext = cursor.Next(ctx)
+
+	if err := cursor.Err(); err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// afterLastFilter builds the filter matching the documents placed after the last fetched one in the sort order:
+// (k1 > v1) OR (k1 == v1 AND k2 > v2) OR ...
+func (p *Paginator[T]) afterLastFilter() (bson.D, error) {
+	values := make([]bson.RawValue, len(p.sort))
+	for i, e := range p.sort {
+		value, err := p.last.LookupErr(strings.Split(e.Key, ".")...)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	or := make(bson.A, 0, len(p.sort))
+	for i, e := range p.sort {
+		cond := make(bson.D, 0, i+1)
+		for j := 0; j < i; j++ {
+			cond = append(cond, bson.E{Key: p.sort[j].Key, Value: values[j]})
+		}
+		operator := "$gt"
+		if isDescending(e.Value) {
+			operator = "$lt"
+		}
+		cond = append(cond, bson.E{Key: e.Key, Value: bson.M{operator: values[i]}})
+		or = append(or, cond)
+	}
+	return bson.D{{Key: "$or", Value: or}}, nil
+}
+
+// isDescending reports whether the sort direction is descending.
+func isDescending(direction interface{}) bool {
+	switch d := direction.(type) {
+	case int:
+		return d < 0
+	case int32:
+		return d < 0
+	case int64:
+		return d < 0
+	case float64:
+		return d < 0
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package mongorepository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPaginator(t *testing.T) {
+	type Item struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Name     string             `bson:"name"`
+		Priority int                `bson:"priority"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Item](db, "items")
+
+	// Several items share the same priority to check the tiebreaker
+	for i := 0; i < 23; i++ {
+		_, err := repo.Create(context.Background(), Item{Name: fmt.Sprintf("item-%02d", i), Priority: i % 4})
+		require.NoError(t, err)
+	}
+
+	paginator := mongorepository.NewPaginator(repo, 5, bson.D{{Key: "priority", Value: -1}})
+
+	var (
+		items []Item
+		pages int
+	)
+	for paginator.HasNext() {
+		page, err := paginator.Next(context.Background())
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(page), 5)
+		items = append(items, page...)
+		pages++
+	}
+	assert.Equal(t, 5, pages)
+	require.Len(t, items, 23)
+
+	// Every document appears exactly once and in order
+	seen := make(map[primitive.ObjectID]bool)
+	for i, item := range items {
+		assert.False(t, seen[item.ID], "duplicate item %s", item.Name)
+		seen[item.ID] = true
+		if i > 0 {
+			prev := items[i-1]
+			assert.GreaterOrEqual(t, prev.Priority, item.Priority)
+			if prev.Priority == item.Priority {
+				assert.Less(t, prev.ID.Hex(), item.ID.Hex())
+			}
+		}
+	}
+
+	// No more pages
+	page, err := paginator.Next(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
@@ -0,0 +1,120 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SlicedResult holds a document with a trimmed array field and the total number of elements in that array.
+type SlicedResult[T any] struct {
+	Document T     `bson:"document"` // Document with the array field trimmed
+	Total    int64 `bson:"total"`    // Total number of elements in the array before trimming
+}
+
+// SizedResult holds a document and its size in bytes when encoded as BSON.
+type SizedResult[T any] struct {
+	Document T     `bson:"document"` // Document as stored in the collection
+	Size     int64 `bson:"size"`     // BSON size of the document in bytes
+}
+
+// FindOneWithSlice returns the first document matching the filters with the array field trimmed
+// to its last n elements, along with the total number of elements in the array, in a single query,
+// e.g. the 20 most recent messages of a conversation and the number of all its messages.
+// Only the trimmed array is sent over the network, so it avoids loading huge arrays.
+// The field must be a top-level array field and n must be positive,
+// otherwise it returns an error with the ErrInvalidSliceSize error code.
+// A missing or null array field is treated as an empty one.
+// If no documents match the filters, it returns an error with the ErrNotFound error code.
+// The function returns a SlicedResult and an error, if any.
+func (r *mongoRepository[T]) FindOneWithSlice(ctx context.Context, field string, n int, filters ...FilterFunc) (_ SlicedResult[T], err error) {
+	ctx, op := r.startOperation(ctx, "FindOneWithSlice")
+	defer func() { op.end(err) }()
+
+	if n <= 0 {
+		return SlicedResult[T]{}, errors.Join(ErrFailedToFindOneByFilter, ErrInvalidSliceSize)
+	}
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	array := bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$limit", Value: 1}},
+		{{Key: "$replaceWith", Value: bson.D{
+			{Key: "document", Value: bson.M{"$mergeObjects": bson.A{
+				"$$ROOT",
+				bson.M{field: bson.M{"$slice": bson.A{array, -n}}},
+			}}},
+			{Key: "total", Value: bson.M{"$size": array}},
+		}}},
+	}
+
+	var result SlicedResult[T]
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Aggregate(ctx, pipeline)
+	})
+	if err != nil {
+		return result, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return result, errors.Join(ErrFailedToFindOneByFilter, err)
+		}
+		return result, errors.Join(ErrFailedToFindOneByFilter, ErrNotFound, mongo.ErrNoDocuments)
+	}
+	if err := cursor.Decode(&result); err != nil {
+		return result, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
+	op.setDocumentCount(1)
+	return result, nil
+}
+
+// FindManyWithSize finds the documents matching the filters, each annotated with its BSON size in bytes,
+// which helps to find the documents approaching the 16MB document size limit.
+// The size is computed by the server with $bsonSize, so it requires MongoDB 4.4 or later.
+// If no documents match the filters, it returns an error with the ErrNotFound error code.
+// The function returns a slice of SizedResult and an error, if any.
+func (r *mongoRepository[T]) FindManyWithSize(ctx context.Context, filters ...FilterFunc) (_ []SizedResult[T], err error) {
+	ctx, op := r.startOperation(ctx, "FindManyWithSize")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$replaceWith", Value: bson.D{
+			{Key: "document", Value: "$$ROOT"},
+			{Key: "size", Value: bson.M{"$bsonSize": "$$ROOT"}},
+		}}},
+	}
+
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Aggregate(ctx, pipeline)
+	})
+	if err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []SizedResult[T]
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+	if len(results) == 0 {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, ErrNotFound)
+	}
+
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
@@ -0,0 +1,102 @@
+package mongorepository_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFindOneWithSlice(t *testing.T) {
+	type Message struct {
+		Text string `bson:"text"`
+	}
+	type Conversation struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Title    string             `bson:"title"`
+		Messages []Message          `bson:"messages"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Conversation](db, "conversations")
+
+	messages := make([]Message, 0, 50)
+	for i := 0; i < 50; i++ {
+		messages = append(messages, Message{Text: fmt.Sprintf("message %d", i)})
+	}
+	_, err := repo.Create(context.Background(), Conversation{Title: "long", Messages: messages})
+	require.NoError(t, err)
+	_, err = repo.Create(context.Background(), Conversation{Title: "empty"})
+	require.NoError(t, err)
+
+	t.Run("LastMessages", func(t *testing.T) {
+		result, err := repo.FindOneWithSlice(context.Background(), "messages", 20, mongorepository.Eq("title", "long"))
+		require.NoError(t, err)
+		assert.Equal(t, "long", result.Document.Title)
+		assert.EqualValues(t, 50, result.Total)
+		require.Len(t, result.Document.Messages, 20)
+		assert.Equal(t, "message 30", result.Document.Messages[0].Text)
+		assert.Equal(t, "message 49", result.Document.Messages[19].Text)
+	})
+
+	t.Run("EmptyArray", func(t *testing.T) {
+		result, err := repo.FindOneWithSlice(context.Background(), "messages", 20, mongorepository.Eq("title", "empty"))
+		require.NoError(t, err)
+		assert.Zero(t, result.Total)
+		assert.Empty(t, result.Document.Messages)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := repo.FindOneWithSlice(context.Background(), "messages", 20, mongorepository.Eq("title", "missing"))
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+	})
+
+	t.Run("InvalidSize", func(t *testing.T) {
+		for _, n := range []int{0, -1} {
+			_, err := repo.FindOneWithSlice(context.Background(), "messages", n, mongorepository.Eq("title", "long"))
+			require.ErrorIs(t, err, mongorepository.ErrInvalidSliceSize)
+		}
+	})
+}
+
+func TestFindManyWithSize(t *testing.T) {
+	type Document struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+		Body string             `bson:"body"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Document](db, "documents")
+
+	_, err := repo.Create(context.Background(), Document{Name: "small", Body: "x"})
+	require.NoError(t, err)
+	_, err = repo.Create(context.Background(), Document{Name: "large", Body: strings.Repeat("x", 1<<16)})
+	require.NoError(t, err)
+
+	results, err := repo.FindManyWithSize(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	sizes := make(map[string]int64, len(results))
+	for _, result := range results {
+		assert.Positive(t, result.Size)
+		sizes[result.Document.Name] = result.Size
+	}
+	assert.Greater(t, sizes["large"], sizes["small"])
+	assert.Greater(t, sizes["large"], int64(1<<16))
+
+	// Filters are applied
+	results, err = repo.FindManyWithSize(context.Background(), mongorepository.Eq("name", "small"))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "x", results[0].Document.Body)
+
+	_, err = repo.FindManyWithSize(context.Background(), mongorepository.Eq("name", "missing"))
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+}
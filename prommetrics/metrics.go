@@ -0,0 +1,74 @@
+// Package prommetrics instruments the repository operations with Prometheus metrics.
+// It is a separate module, so the Prometheus client is only pulled in by the applications using it.
+package prommetrics
+
+import (
+	"errors"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Operation statuses used as the status label values.
+const (
+	StatusOK       = "ok"
+	StatusNotFound = "not_found"
+	StatusError    = "error"
+)
+
+// WithMetrics records the repository operations in the given registerer:
+//   - mongo_repo_operations_total{op,status}, the number of operations by their status,
+//     i.e. "ok", "not_found" for the errors with the ErrNotFound error code, or "error";
+//   - mongo_repo_operation_duration_seconds{op}, the histogram of the operation latencies.
+//
+// The metrics are registered once per registerer, so the repositories sharing the registerer record
+// into the same collectors. It is implemented as a query hook, so it composes with the other hooks set by WithQueryHook.
+// It panics if the metrics can't be registered, e.g. if other collectors with the same names are registered.
+func WithMetrics(registerer prometheus.Registerer) mongorepository.Option {
+	return mongorepository.WithQueryHook(newHook(registerer))
+}
+
+// newHook registers the metrics and returns the query hook recording them.
+func newHook(registerer prometheus.Registerer) mongorepository.QueryHook {
+	operations := register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_repo_operations_total",
+		Help: "Number of MongoDB repository operations by operation and status.",
+	}, []string{"op", "status"}))
+	duration := register(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mongo_repo_operation_duration_seconds",
+		Help:    "Latency of MongoDB repository operations in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"op"}))
+
+	return func(op string, dur time.Duration, err error) {
+		operations.WithLabelValues(op, status(err)).Inc()
+		duration.WithLabelValues(op).Observe(dur.Seconds())
+	}
+}
+
+// register registers the collector, or returns the already registered one with the same descriptor.
+func register[C prometheus.Collector](registerer prometheus.Registerer, collector C) C {
+	if err := registerer.Register(collector); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return collector
+}
+
+// status returns the status label value for the operation error.
+func status(err error) string {
+	switch {
+	case err == nil:
+		return StatusOK
+	case errors.Is(err, mongorepository.ErrNotFound):
+		return StatusNotFound
+	default:
+		return StatusError
+	}
+}
@@ -0,0 +1,119 @@
+package mongorepository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// QueryOption configures a single query, e.g. its collation or sort order.
+// Since the repository methods take variadic filters, query options are passed
+// through the context created by WithQueryOptions.
+type QueryOption func(*queryOptions)
+
+// queryOptions holds the query configuration set by the QueryOption(s).
+type queryOptions struct {
+	collation *options.Collation
+	sort      bson.D
+	hint      string
+}
+
+// queryOptionsKey is the context key of the query options.
+type queryOptionsKey struct{}
+
+// WithQueryOptions returns a copy of the context carrying the query options,
+// which are applied by the repository methods called with that context.
+// Options already carried by the parent context are kept, unless overridden by the new ones.
+//
+//	ctx = mongorepository.WithQueryOptions(ctx, mongorepository.WithCollation(&options.Collation{Locale: "en", Strength: 2}))
+//	users, err := repo.FindManyByFilter(ctx, 0, 10)
+func WithQueryOptions(ctx context.Context, opts ...QueryOption) context.Context {
+	qopts := queryOptionsFromContext(ctx)
+	for _, opt := range opts {
+		opt(&qopts)
+	}
+	return context.WithValue(ctx, queryOptionsKey{}, qopts)
+}
+
+// queryOptionsFromContext returns the query options carried by the context, if any.
+func queryOptionsFromContext(ctx context.Context) queryOptions {
+	qopts, _ := ctx.Value(queryOptionsKey{}).(queryOptions)
+	return qopts
+}
+
+// WithCollation sets the collation used to compare and sort strings, e.g. {Locale: "en", Strength: 2}
+// for case-insensitive matching and sorting. It is applied to FindManyByFilter, FindOneByFilter,
+// Count and DistinctWithCounts. Note that an index is only used by a query with the same collation.
+func WithCollation(collation *options.Collation) QueryOption {
+	return func(opts *queryOptions) {
+		opts.collation = collation
+	}
+}
+
+// WithSort sets the sort order of the documents returned by FindManyByFilter and FindOneByFilter,
+// e.g. bson.D{{Key: "name", Value: 1}} to sort by name in ascending order.
+func WithSort(sort bson.D) QueryOption {
+	return func(opts *queryOptions) {
+		opts.sort = sort
+	}
+}
+
+// WithHint forces the query to use the index with the given name, e.g. when the query planner picks a bad plan.
+// It is applied to FindManyByFilter, FindOneByFilter, Count and DistinctWithCounts.
+// The query fails if the index doesn't exist.
+func WithHint(indexName string) QueryOption {
+	return func(opts *queryOptions) {
+		opts.hint = indexName
+	}
+}
+
+// findOptions applies the query options to the find options.
+func (q queryOptions) findOptions(opts *options.FindOptions) *options.FindOptions {
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+	if q.sort != nil {
+		opts.SetSort(q.sort)
+	}
+	if q.hint != "" {
+		opts.SetHint(q.hint)
+	}
+	return opts
+}
+
+// findOneOptions applies the query options to the find one options.
+func (q queryOptions) findOneOptions(opts *options.FindOneOptions) *options.FindOneOptions {
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+	if q.sort != nil {
+		opts.SetSort(q.sort)
+	}
+	if q.hint != "" {
+		opts.SetHint(q.hint)
+	}
+	return opts
+}
+
+// countOptions applies the query options to the count options.
+func (q queryOptions) countOptions(opts *options.CountOptions) *options.CountOptions {
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+	if q.hint != "" {
+		opts.SetHint(q.hint)
+	}
+	return opts
+}
+
+// aggregateOptions applies the query options to the aggregate options.
+func (q queryOptions) aggregateOptions(opts *options.AggregateOptions) *options.AggregateOptions {
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+	if q.hint != "" {
+		opts.SetHint(q.hint)
+	}
+	return opts
+}
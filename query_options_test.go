@@ -0,0 +1,159 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestWithCollation(t *testing.T) {
+	type User struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Username string             `bson:"username"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	for _, username := range []string{"bob", "Charlie", "alice", "Bob"} {
+		_, err := repo.Create(context.Background(), User{Username: username})
+		require.NoError(t, err)
+	}
+
+	byUsername := mongorepository.WithSort(bson.D{{Key: "username", Value: 1}, {Key: "_id", Value: 1}})
+	usernames := func(users []User) []string {
+		result := make([]string, 0, len(users))
+		for _, u := range users {
+			result = append(result, u.Username)
+		}
+		return result
+	}
+
+	t.Run("BinarySort", func(t *testing.T) {
+		ctx := mongorepository.WithQueryOptions(context.Background(), byUsername)
+		users, err := repo.FindManyByFilter(ctx, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bob", "Charlie", "alice", "bob"}, usernames(users))
+	})
+
+	ctx := mongorepository.WithQueryOptions(
+		context.Background(),
+		byUsername,
+		mongorepository.WithCollation(&options.Collation{Locale: "en", Strength: 2}),
+	)
+
+	t.Run("FindManyByFilter", func(t *testing.T) {
+		users, err := repo.FindManyByFilter(ctx, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice", "bob", "Bob", "Charlie"}, usernames(users))
+	})
+
+	t.Run("FindOneByFilter", func(t *testing.T) {
+		user, err := repo.FindOneByFilter(ctx, mongorepository.Eq("username", "CHARLIE"))
+		require.NoError(t, err)
+		assert.Equal(t, "Charlie", user.Username)
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		count, err := repo.Count(ctx, mongorepository.Eq("username", "BOB"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		// Without collation the comparison is case-sensitive
+		count, err = repo.Count(context.Background(), mongorepository.Eq("username", "BOB"))
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("DistinctWithCounts", func(t *testing.T) {
+		counts, err := repo.DistinctWithCounts(ctx, "username")
+		require.NoError(t, err)
+		require.Len(t, counts, 3)
+		assert.Equal(t, int64(2), counts[0].Count)
+	})
+}
+
+func TestWithHint(t *testing.T) {
+	type User struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty"`
+		Username string             `bson:"username"`
+		Status   string             `bson:"status"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	require.NoError(t, repo.CreateIndex(context.Background(), "status", mongorepository.Name("status_idx")))
+
+	for _, username := range []string{"john", "jane"} {
+		_, err := repo.Create(context.Background(), User{Username: username, Status: "active"})
+		require.NoError(t, err)
+	}
+
+	t.Run("ExistingIndex", func(t *testing.T) {
+		ctx := mongorepository.WithQueryOptions(context.Background(), mongorepository.WithHint("status_idx"))
+
+		users, err := repo.FindManyByFilter(ctx, 0, 0, mongorepository.Eq("username", "john"))
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+
+		count, err := repo.Count(ctx, mongorepository.Eq("status", "active"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	// The server rejects a hint of a missing index, which proves the hint is passed through
+	t.Run("MissingIndex", func(t *testing.T) {
+		ctx := mongorepository.WithQueryOptions(context.Background(), mongorepository.WithHint("missing_idx"))
+
+		_, err := repo.FindManyByFilter(ctx, 0, 0, mongorepository.Eq("username", "john"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "hint")
+
+		_, err = repo.Count(ctx, mongorepository.Eq("status", "active"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "hint")
+	})
+}
+
+func TestCountAffected(t *testing.T) {
+	type User struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Name   string             `bson:"name"`
+		Status string             `bson:"status"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	for _, user := range []User{
+		{Name: "John", Status: "inactive"},
+		{Name: "Jane", Status: "inactive"},
+		{Name: "Alex", Status: "active"},
+	} {
+		_, err := repo.Create(context.Background(), user)
+		require.NoError(t, err)
+	}
+
+	affected, err := repo.CountAffected(context.Background(), mongorepository.Eq("status", "inactive"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+
+	// Nothing is modified
+	count, err := repo.Count(context.Background(), mongorepository.Eq("status", "inactive"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	count, err = repo.Count(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	// The same filters affect the same documents
+	modified, err := repo.UpdateMany(context.Background(), map[string]interface{}{"status": "archived"}, mongorepository.Eq("status", "inactive"))
+	require.NoError(t, err)
+	assert.Equal(t, affected, modified)
+}
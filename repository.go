@@ -1,13 +1,21 @@
 package mongorepository
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/x/mongo/driver"
 )
 
 // Repository is an interface that defines the methods for interacting with a MongoDB collection.
@@ -18,11 +26,50 @@ type Repository[T any] interface {
 	// The function returns an error if the index creation fails.
 	CreateIndex(ctx context.Context, key interface{}, opts ...IndexOption) error
 
+	// CreateCompoundIndex creates an index on several fields, each with its own sort order.
+	// It takes a context.Context, the index keys and optional IndexOption(s).
+	// The function returns an error if the index creation fails.
+	CreateCompoundIndex(ctx context.Context, keys []IndexKey, opts ...IndexOption) error
+
+	// CreateSoftDeleteAwareUniqueIndex creates an index enforcing the uniqueness of the field
+	// among the documents which are not soft-deleted, i.e. don't have the deletedField set.
+	// The function returns an error if the index creation fails.
+	CreateSoftDeleteAwareUniqueIndex(ctx context.Context, field, deletedField string) error
+
+	// ListIndexes returns all indexes of the MongoDB collection, including the default _id index.
+	// It takes a context.Context as the only argument.
+	// The function returns a slice of IndexInfo and an error, if any.
+	ListIndexes(ctx context.Context) ([]IndexInfo, error)
+
+	// EnsureIndex creates an index in the MongoDB collection the same way as CreateIndex,
+	// but it is safe to call on every application start.
+	// An existing index with matching options is kept, a conflicting one is dropped and recreated.
+	// The function returns an error if the index creation fails.
+	EnsureIndex(ctx context.Context, key string, opts ...IndexOption) error
+
+	// DropIndex drops the index with the given name from the MongoDB collection.
+	// It takes a context.Context and the name of the index as parameters.
+	// The function returns an error if the index deletion fails.
+	DropIndex(ctx context.Context, name string) error
+
 	// Create inserts a new document into the MongoDB collection.
 	// It takes a context.Context and a model of type T as input parameters.
 	// It returns the ID of the newly created document as a string and an error, if any.
 	Create(ctx context.Context, model T) (string, error)
 
+	// CreateIdempotent inserts a new document with the given idempotency key, unless a document
+	// with the same key already exists, e.g. when a client retries a request.
+	// It returns the ID of the new or existing document, whether it was created and an error, if any.
+	CreateIdempotent(ctx context.Context, model T, idempotencyKey string) (string, bool, error)
+
+	// CreateUnacknowledged inserts a new document without waiting for the server to acknowledge the write.
+	// It returns an error if the document can't be sent to the server.
+	CreateUnacknowledged(ctx context.Context, model T) error
+
+	// FindOrCreate returns the document matching the filters, inserting the model if there is none.
+	// It returns the found or created document, whether it was created and an error, if any.
+	FindOrCreate(ctx context.Context, model T, filters ...FilterFunc) (T, bool, error)
+
 	// FindByID retrieves a document from the MongoDB collection by its ID.
 	// It takes a context.Context and the ID of the document as parameters.
 	// It returns the retrieved document of type T and an error, if any.
@@ -33,11 +80,43 @@ type Repository[T any] interface {
 	// It returns a slice of documents of type T and an error, if any.
 	FindByIDs(ctx context.Context, ids ...string) ([]T, error)
 
+	// FindByObjectIDsMap retrieves multiple documents from the MongoDB collection by their ObjectIDs.
+	// It returns the documents keyed by their ObjectIDs, so callers which already have ObjectIDs,
+	// e.g. from reference arrays, avoid hex conversions. Missing documents are absent from the map.
+	FindByObjectIDsMap(ctx context.Context, ids ...primitive.ObjectID) (map[primitive.ObjectID]T, error)
+
+	// FindByIDsOrdered retrieves multiple documents from the MongoDB collection by their IDs,
+	// returned in the order of the given ids. The ids without a matching document are handled
+	// according to the missing policy.
+	// It returns a slice of documents of type T and an error, if any.
+	FindByIDsOrdered(ctx context.Context, missing MissingIDsPolicy, ids ...string) ([]T, error)
+
 	// Update updates a document in the MongoDB collection with the specified ID.
 	// It takes a context, ID string, and model as input parameters.
 	// It returns the number of modified documents and an error, if any.
 	Update(ctx context.Context, id string, model T) (int64, error)
 
+	// Replace replaces the whole document with the specified ID by the model, keeping the _id intact.
+	// Unlike Update, fields absent from the model are removed from the stored document.
+	// It returns the number of modified documents and an error, if any.
+	Replace(ctx context.Context, id string, model T) (int64, error)
+
+	// UpdateAndReturn updates a document with the specified ID the same way as Update,
+	// and returns the resulting document along with whether the update actually changed anything.
+	// If the document does not exist, it returns an error with the ErrNotFound error code.
+	UpdateAndReturn(ctx context.Context, id string, model T) (doc T, modified bool, err error)
+
+	// UpdateVersioned updates a document with the specified ID only if its version field equals expectedVersion,
+	// and increments the version, to prevent lost updates.
+	// If the document exists but has another version, it returns an error with the ErrVersionConflict error code.
+	// It returns the number of modified documents and an error, if any.
+	UpdateVersioned(ctx context.Context, id string, model T, expectedVersion int64) (int64, error)
+
+	// UpdateFields sets the given fields of the document with the given ID, leaving the other fields intact.
+	// Dotted keys, e.g. "address.city", update a single field of an embedded document.
+	// It returns the number of modified documents and an error, if any.
+	UpdateFields(ctx context.Context, id string, fields map[string]interface{}) (int64, error)
+
 	// UpdateMany updates multiple documents in the MongoDB collection based on the provided filters.
 	// It takes a context.Context, a map of update fields, and optional filter functions as parameters.
 	// The update fields specify the changes to be made to the documents.
@@ -45,14 +124,46 @@ type Repository[T any] interface {
 	// It returns the number of documents modified and an error if any.
 	UpdateMany(ctx context.Context, update map[string]interface{}, filters ...FilterFunc) (int64, error)
 
+	// UpdateOneByFilter updates a single document matching the provided filters.
+	// It returns the number of documents modified and an error if any.
+	UpdateOneByFilter(ctx context.Context, update map[string]interface{}, filters ...FilterFunc) (int64, error)
+
+	// CopyField copies the value of the "from" field into the "to" field for all documents matching the filters.
+	// It uses an update with an aggregation pipeline, so it requires MongoDB 4.2 or later.
+	// It returns the number of documents modified and an error if any.
+	CopyField(ctx context.Context, from, to string, filters ...FilterFunc) (int64, error)
+
+	// RenameField renames the "from" field to "to" in all documents matching the filters, e.g. for schema migrations.
+	// It returns the number of documents modified and an error if any.
+	RenameField(ctx context.Context, from, to string, filters ...FilterFunc) (int64, error)
+
+	// NormalizeStringField transforms the string values of the field in all documents matching the filters
+	// according to the given mode in a single operation, e.g. to lowercase all existing emails.
+	// It returns the number of documents modified and an error if any.
+	NormalizeStringField(ctx context.Context, field string, mode StringNormalizeMode, filters ...FilterFunc) (int64, error)
+
+	// CompareAndSet atomically sets the field of the document with the specified ID to newValue,
+	// but only if the field currently equals the expected value.
+	// It returns true if the value was set and false if the precondition did not hold, and an error, if any.
+	CompareAndSet(ctx context.Context, id string, field string, expected, newValue interface{}) (bool, error)
+
 	// Delete deletes a document from the MongoDB collection based on the provided ID.
 	// It returns the number of deleted documents and an error, if any.
 	Delete(ctx context.Context, id string) (int64, error)
 
+	// DeleteByIDs deletes the documents with the given IDs from the MongoDB collection.
+	// All ids are validated before deleting anything.
+	// It returns the number of deleted documents and an error, if any.
+	DeleteByIDs(ctx context.Context, ids ...string) (int64, error)
+
 	// DeleteMany deletes multiple documents from the MongoDB collection based on the provided filters.
 	// It returns the number of deleted documents and an error, if any.
 	DeleteMany(ctx context.Context, filters ...FilterFunc) (int64, error)
 
+	// DeleteManyReturningIDs deletes multiple documents from the MongoDB collection based on the provided filters.
+	// It returns the IDs of the deleted documents and an error, if any.
+	DeleteManyReturningIDs(ctx context.Context, filters ...FilterFunc) ([]string, error)
+
 	// FindManyByFilter retrieves multiple documents from the collection based on the provided filters.
 	// It allows skipping a certain number of documents and limiting the number of documents to be returned.
 	// The filters are applied in the order they are passed.
@@ -61,6 +172,37 @@ type Repository[T any] interface {
 	// The function returns a slice of documents of type T and an error.
 	FindManyByFilter(ctx context.Context, skip int64, limit int64, filters ...FilterFunc) ([]T, error)
 
+	// FindManyByFilterWithConfig works as FindManyByFilter, but applies the FindConfig(s), e.g. BatchSize
+	// and MaxTime, to the cursor, e.g. to tune large result sets.
+	// The function returns a slice of documents of type T and an error.
+	FindManyByFilterWithConfig(ctx context.Context, skip, limit int64, config []FindConfig, filters ...FilterFunc) ([]T, error)
+
+	// CachedFindMany works as FindManyByFilter, but caches the results in memory for the given TTL.
+	// Repeated identical queries return the cached results without hitting the database until the TTL expires.
+	CachedFindMany(ctx context.Context, ttl time.Duration, skip, limit int64, filters ...FilterFunc) ([]T, error)
+
+	// FindInto finds all documents matching the filters and decodes them into the caller-provided slice,
+	// reusing its capacity, so that hot paths can pool the slices instead of allocating a new one per call.
+	// The slice is truncated first, and it's left empty if an error occurs.
+	// The function returns an error, if any.
+	FindInto(ctx context.Context, dst *[]T, filters ...FilterFunc) error
+
+	// FindDuplicatesOf computes the hash of the model with hashFn and returns the stored documents
+	// having the same value in hashField.
+	// Unlike FindManyByFilter, it returns an empty slice without an error if there are no duplicates.
+	FindDuplicatesOf(ctx context.Context, hashField string, model T, hashFn func(T) string) ([]T, error)
+
+	// Tail follows a capped collection in real time: it calls fn for every document matching the filters
+	// as they arrive, until the context is cancelled or fn returns an error.
+	Tail(ctx context.Context, fn func(T) error, filters ...FilterFunc) error
+
+	// Watch calls fn for every change of the documents in the collection until the context is cancelled
+	// or fn returns an error. The optional pipeline stages filter the change events.
+	Watch(ctx context.Context, fn func(ChangeEvent[T]) error, pipeline ...bson.D) error
+
+	// WatchFrom works as Watch, but resumes watching right after the event with the given resume token.
+	WatchFrom(ctx context.Context, resumeToken bson.Raw, fn func(ChangeEvent[T]) error, pipeline ...bson.D) error
+
 	// FindOneByFilter finds a single document in the collection based on the provided filters.
 	// It accepts one or more FilterFunc functions that modify the filter criteria.
 	// The function returns the found document of type T and an error, if any.
@@ -74,38 +216,187 @@ type Repository[T any] interface {
 	// If an error occurs during the find operation, it returns the error.
 	Exists(ctx context.Context, filters ...FilterFunc) (bool, error)
 
+	// WouldViolateUnique reports whether a document with the given field value already exists
+	// among the documents matching the filters, i.e. the scope of the unique constraint.
+	// The function returns a boolean and an error, if any.
+	WouldViolateUnique(ctx context.Context, field string, value interface{}, filters ...FilterFunc) (bool, error)
+
 	// Count returns the number of documents in the collection based on the provided filters.
 	// It accepts one or more FilterFunc functions that modify the filter criteria.
 	// The function returns the number of documents and an error, if any.
 	Count(ctx context.Context, filters ...FilterFunc) (int64, error)
+
+	// CountAffected returns the number of documents a write with the same filters, e.g. UpdateMany or DeleteMany,
+	// would affect, without modifying anything, e.g. to check a mass update before running it in production.
+	// The function returns the number of matching documents and an error, if any.
+	CountAffected(ctx context.Context, filters ...FilterFunc) (int64, error)
+
+	// EstimatedCount returns an estimated number of documents in the whole collection.
+	// It uses the collection metadata instead of scanning documents, so it is fast on huge collections,
+	// but it can't take filters and may be inaccurate, e.g. after an unclean shutdown.
+	// The function returns the number of documents and an error, if any.
+	EstimatedCount(ctx context.Context) (int64, error)
+
+	// DistinctWithCounts returns the distinct values of the field among the documents matching the filters,
+	// along with the number of documents having each value, ordered from the most to the least common.
+	// Both scalar and array fields are supported: each element of an array is counted as a separate value.
+	// The function returns a slice of ValueCount and an error, if any.
+	DistinctWithCounts(ctx context.Context, field string, filters ...FilterFunc) ([]ValueCount, error)
+
+	// MaxTime returns the newest time value of the given date field among the documents matching the filters.
+	// It uses a sort with limit 1 instead of an aggregation, so an index on the field makes it cheap.
+	// If no documents match the filters, it returns an error with the ErrNotFound error code.
+	MaxTime(ctx context.Context, field string, filters ...FilterFunc) (time.Time, error)
+
+	// MinTime returns the oldest time value of the given date field among the documents matching the filters.
+	// It uses a sort with limit 1 instead of an aggregation, so an index on the field makes it cheap.
+	// If no documents match the filters, it returns an error with the ErrNotFound error code.
+	MinTime(ctx context.Context, field string, filters ...FilterFunc) (time.Time, error)
+	// FindOneWithSlice returns the first document matching the filters with the array field
+	// trimmed to its last n elements, along with the total number of elements in the array.
+	// If no documents match the filters, it returns an error with the ErrNotFound error code.
+	FindOneWithSlice(ctx context.Context, field string, n int, filters ...FilterFunc) (SlicedResult[T], error)
+
+	// Facet counts the documents matching the filters per distinct value of the groupBy field in one round trip.
+	// The map is keyed by the string form of the group value.
+	// The function returns a map of the group values to the document counts and an error, if any.
+	Facet(ctx context.Context, groupBy string, filters ...FilterFunc) (map[string]int64, error)
+
+	// FindManyWithSize finds the documents matching the filters, each annotated with its BSON size in bytes.
+	// If no documents match the filters, it returns an error with the ErrNotFound error code.
+	// The function returns a slice of SizedResult and an error, if any.
+	FindManyWithSize(ctx context.Context, filters ...FilterFunc) ([]SizedResult[T], error)
+
+	// BulkWrite executes mixed insert, update and delete operations, created by InsertOp, UpdateOp and DeleteOp,
+	// in one round trip. If ordered is true, the execution stops at the first failed operation.
+	// The function returns a BulkResult and an error, if any.
+	BulkWrite(ctx context.Context, ops []WriteOp, ordered bool) (BulkResult, error)
+
+	// ValidateDocument checks the model against the collection validator, e.g. a $jsonSchema, without writing it.
+	// If the document is invalid, it returns an error with the ErrValidation and ErrSchemaMismatch error codes.
+	ValidateDocument(ctx context.Context, model T) error
+
+	// CountByTimeBucket counts the documents matching the filters per time bucket of the date field,
+	// ordered from the oldest to the newest bucket. The bucket boundaries are computed in the given timezone.
+	// The function returns a slice of TimeBucket and an error, if any.
+	CountByTimeBucket(ctx context.Context, field, unit, timezone string, filters ...FilterFunc) ([]TimeBucket, error)
+
+	// JoinStream streams the documents matching the filters, joined with the documents of the from collection
+	// with $lookup, through the callback one by one, without buffering the whole result set.
+	// The function returns an error if the aggregation or the callback fails.
+	JoinStream(ctx context.Context, from, localField, foreignField, as string, fn func(bson.M) error, filters ...FilterFunc) error
+
+	// Explain returns the query plan of a find with the given filters with the "executionStats" verbosity.
+	// The function returns the raw explain result and an error, if any.
+	Explain(ctx context.Context, filters ...FilterFunc) (bson.M, error)
+
+	// FindPageFaceted returns a page of the documents matching the filters along with the total number
+	// of matching documents in a single round trip.
+	// The function returns the page documents, the total number of matching documents and an error, if any.
+	FindPageFaceted(ctx context.Context, skip, limit int64, filters ...FilterFunc) ([]T, int64, error)
+
+	// CountByPeriod counts the documents matching the filters per period of the date field,
+	// ordered from the oldest to the newest period. The granularity is one of "day", "month" or "year".
+	// The function returns a slice of PeriodCount and an error, if any.
+	CountByPeriod(ctx context.Context, dateField string, granularity string, filters ...FilterFunc) ([]PeriodCount, error)
+
+	// Aggregate runs the aggregation pipeline on the collection and returns the cursor over its results,
+	// which the caller must close. See AggregateTyped to decode the results into a typed slice.
+	// It returns an error with the ErrFailedToAggregate error code if the aggregation fails.
+	Aggregate(ctx context.Context, pipeline mongo.Pipeline) (*mongo.Cursor, error)
+
+	// UpdateManyOps applies the update operators, e.g. AddToSet and Pull, to all documents matching the filters.
+	// It returns the number of documents modified and an error if any.
+	UpdateManyOps(ctx context.Context, ops []UpdateFunc, filters ...FilterFunc) (int64, error)
+
+	// ForDatabase returns a repository on the same collection name in the given database,
+	// preserving the repository options, e.g. for deployments isolating tenants by database.
+	ForDatabase(db *mongo.Database) Repository[T]
+
+	// Ping verifies the connection to the database of the repository, e.g. for readiness probes.
+	// It returns an error with the ErrFailedToConnect error code if the database can't be reached.
+	Ping(ctx context.Context) error
+
+	// Stats returns the storage statistics of the collection, e.g. the number of documents and the index sizes.
+	// It returns an error if the collStats command fails.
+	Stats(ctx context.Context) (CollStats, error)
+
+	// Sum sums the numeric sumField of the documents matching the filters per distinct value of the groupBy field.
+	// The function returns a map of the group values to the sums and an error, if any.
+	Sum(ctx context.Context, groupBy, sumField string, filters ...FilterFunc) (map[string]float64, error)
+
+	// Avg averages the numeric avgField of the documents matching the filters per distinct value of the groupBy field.
+	// The function returns a map of the group values to the averages and an error, if any.
+	Avg(ctx context.Context, groupBy, avgField string, filters ...FilterFunc) (map[string]float64, error)
+
+	// UpsertMany inserts or updates the models keyed by the value of keyField, e.g. an external id,
+	// in one round trip. The function returns a BulkResult and an error, if any.
+	UpsertMany(ctx context.Context, keyField string, models []T) (BulkResult, error)
 }
 
+// versionField is the name of the document field used for optimistic concurrency control.
+const versionField = "version"
+
+// idempotencyKeyField is the name of the document field holding the idempotency key set by CreateIdempotent.
+const idempotencyKeyField = "idempotency_key"
+
 // mongoRepository is a generic struct that represents a MongoDB repository.
 // It holds a reference to a mongo.Collection, which is used to interact with the MongoDB database.
 type mongoRepository[T any] struct {
 	collection *mongo.Collection
+	opts       repositoryOptions
+	cache      *resultCache[T]
 }
 
 // NewMongoRepository creates a new instance of the mongoRepository[T] struct.
-// It takes a mongo.Database and a collectionName as parameters and returns a pointer to the mongoRepository[T] struct.
+// It takes a mongo.Database, a collectionName and optional Option(s) as parameters
+// and returns a pointer to the mongoRepository[T] struct.
 // The mongoRepository[T] struct represents a repository for working with a specific MongoDB collection.
 // The collection field of the struct is initialized with the specified collectionName from the provided database.
-func NewMongoRepository[T any](db *mongo.Database, collectionName string) *mongoRepository[T] {
-	return &mongoRepository[T]{collection: db.Collection(collectionName)}
+func NewMongoRepository[T any](db *mongo.Database, collectionName string, opts ...Option) *mongoRepository[T] {
+	repo := &mongoRepository[T]{
+		collection: db.Collection(collectionName),
+		cache:      newResultCache[T](),
+	}
+	for _, opt := range opts {
+		opt(&repo.opts)
+	}
+	return repo
+}
+
+// ForDatabase returns a repository on the same collection name in the given database,
+// e.g. for deployments isolating tenants by database rather than by collection.
+// The returned repository shares the options of this repository, such as the query hook, tracer and retries,
+// but has its own result cache, so cached results never leak across databases.
+func (r *mongoRepository[T]) ForDatabase(db *mongo.Database) Repository[T] {
+	return &mongoRepository[T]{
+		collection: db.Collection(r.collection.Name()),
+		opts:       r.opts,
+		cache:      newResultCache[T](),
+	}
 }
 
 // CreateIndex creates an index in the MongoDB collection based on the specified key and options.
 // It takes a context.Context as the first argument, the key for the index as the second argument,
 // and optional IndexOption(s) as the third argument(s).
+// The key is either a field name, indexed in ascending order, or an index keys document,
+// e.g. bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}} for a compound index.
 // The function returns an error if the index creation fails.
-func (r *mongoRepository[T]) CreateIndex(ctx context.Context, key string, opts ...IndexOption) error {
+func (r *mongoRepository[T]) CreateIndex(ctx context.Context, key interface{}, opts ...IndexOption) (err error) {
+	ctx, op := r.startOperation(ctx, "CreateIndex")
+	defer func() { op.end(err) }()
+
 	indexOpts := options.Index()
 	for _, opt := range opts {
 		opt(indexOpts)
 	}
 
+	keys := key
+	if field, ok := key.(string); ok {
+		keys = bson.D{{Key: field, Value: 1}}
+	}
 	indexModel := mongo.IndexModel{
-		Keys:    bson.D{{Key: key, Value: 1}},
+		Keys:    keys,
 		Options: indexOpts,
 	}
 
@@ -118,33 +409,228 @@ func (r *mongoRepository[T]) CreateIndex(ctx context.Context, key string, opts .
 // Create inserts a new document into the MongoDB collection.
 // It takes a context.Context and a model of type T as input parameters.
 // It returns the ID of the newly created document as a string and an error, if any.
-func (r *mongoRepository[T]) Create(ctx context.Context, model T) (string, error) {
+// If the model carries a preset _id, e.g. a client-generated ObjectID, string or UUID, that ID is returned.
+// If the model implements the Validator interface, invalid models are rejected with the ErrValidation error.
+// Documents exceeding the 16MB BSON size limit are rejected with the ErrDocumentTooLarge error.
+func (r *mongoRepository[T]) Create(ctx context.Context, model T) (_ string, err error) {
+	ctx, op := r.startOperation(ctx, "Create")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return "", errors.Join(ErrFailedToCreate, err)
+	}
+
 	result, err := r.collection.InsertOne(ctx, model)
 	if err != nil {
 		// Handle duplicate key error
 		if mongo.IsDuplicateKeyError(err) {
 			return "", errors.Join(ErrFailedToCreate, ErrDuplicate, err)
 		}
+		if isDocumentTooLargeError(err) {
+			return "", errors.Join(ErrFailedToCreate, ErrDocumentTooLarge, err)
+		}
 		return "", errors.Join(ErrFailedToCreate, err)
 	}
-	oid, ok := result.InsertedID.(primitive.ObjectID)
+	id, ok := idString(result.InsertedID)
 	if !ok {
-		return "", errors.Join(ErrInvalidDocumentID, err)
+		return "", errors.Join(ErrFailedToCreate, ErrInvalidDocumentID, fmt.Errorf("unsupported _id type %T", result.InsertedID))
+	}
+	return id, nil
+}
+
+// idString returns the string form of the inserted document ID, which is the ID preset on the model, if any,
+// or the ObjectID generated by the driver: ObjectIDs are returned as hex strings, strings as is,
+// and UUIDs stored as binary subtype 4 in their canonical form.
+// It reports false if the ID has another type.
+func idString(id interface{}) (string, bool) {
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		return v.Hex(), true
+	case string:
+		return v, true
+	case primitive.Binary:
+		if v.Subtype != bson.TypeBinaryUUID || len(v.Data) != 16 {
+			return "", false
+		}
+		d := hex.EncodeToString(v.Data)
+		return d[:8] + "-" + d[8:12] + "-" + d[12:16] + "-" + d[16:20] + "-" + d[20:], true
+	default:
+		return "", false
+	}
+}
+
+// CreateUnacknowledged inserts a new document with the unacknowledged write concern (w: 0),
+// without waiting for the server to acknowledge the write, e.g. for fire-and-forget bulk imports.
+// The write concern of the collection used by the other methods is left unchanged.
+// It trades durability for throughput: the write isn't confirmed to be applied, so server-side failures,
+// e.g. a duplicate key or a validation error, go unnoticed, and the document may be lost on a failover.
+// The document is also not guaranteed to be visible to a read right after the call.
+// Unacknowledged writes are not allowed in sessions and transactions.
+// If the model implements the Validator interface, invalid models are rejected with the ErrValidation error.
+// It returns an error if the document can't be sent to the server.
+func (r *mongoRepository[T]) CreateUnacknowledged(ctx context.Context, model T) (err error) {
+	ctx, op := r.startOperation(ctx, "CreateUnacknowledged")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return errors.Join(ErrFailedToCreate, err)
+	}
+
+	collection, err := r.collection.Clone(options.Collection().SetWriteConcern(writeconcern.Unacknowledged()))
+	if err != nil {
+		return errors.Join(ErrFailedToCreate, err)
+	}
+	if _, err := collection.InsertOne(ctx, model); err != nil && !errors.Is(err, mongo.ErrUnacknowledgedWrite) {
+		if isDocumentTooLargeError(err) {
+			return errors.Join(ErrFailedToCreate, ErrDocumentTooLarge, err)
+		}
+		return errors.Join(ErrFailedToCreate, err)
+	}
+	return nil
+}
+
+// CreateIdempotent inserts a new document with the given idempotency key stored in the idempotency_key field,
+// unless a document with the same key already exists, so that the writes retried by clients with at-least-once
+// delivery don't create duplicate records. On a retry, the existing document is left untouched and its ID is returned.
+// A unique index on the idempotency_key field is required to deduplicate concurrent retries, e.g.
+// repo.EnsureIndex(ctx, "idempotency_key", Unique(true)).
+// If the model implements the Validator interface, invalid models are rejected with the ErrValidation error.
+// It returns the ID of the new or existing document, whether it was created and an error, if any.
+func (r *mongoRepository[T]) CreateIdempotent(ctx context.Context, model T, idempotencyKey string) (_ string, created bool, err error) {
+	ctx, op := r.startOperation(ctx, "CreateIdempotent")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return "", false, errors.Join(ErrFailedToCreate, err)
+	}
+
+	doc, err := toBsonD(model)
+	if err != nil {
+		return "", false, errors.Join(ErrFailedToCreate, err)
+	}
+	// The key is set from the filter on insert
+	insert := make(bson.D, 0, len(doc))
+	for _, e := range doc {
+		if e.Key != idempotencyKeyField {
+			insert = append(insert, e)
+		}
+	}
+
+	filter := bson.D{{Key: idempotencyKeyField, Value: idempotencyKey}}
+	update := bson.D{{Key: "$setOnInsert", Value: insert}}
+	result, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	// A concurrent retry may have inserted the document in the meantime
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		if isDocumentTooLargeError(err) {
+			return "", false, errors.Join(ErrFailedToCreate, ErrDocumentTooLarge, err)
+		}
+		return "", false, errors.Join(ErrFailedToCreate, err)
+	}
+	if err == nil && result.UpsertedID != nil {
+		oid, ok := result.UpsertedID.(primitive.ObjectID)
+		if !ok {
+			return "", false, errors.Join(ErrFailedToCreate, ErrInvalidDocumentID)
+		}
+		op.setDocumentCount(1)
+		return oid.Hex(), true, nil
+	}
+
+	// The document already exists, so return its ID
+	var existing struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := r.collection.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(&existing); err != nil {
+		return "", false, errors.Join(ErrFailedToCreate, err)
+	}
+	return existing.ID.Hex(), false, nil
+}
+
+// FindOrCreate returns the document matching the filters, inserting the model if there is none,
+// e.g. for idempotent creation keyed by a unique field. The lookup and the insertion are done atomically
+// with a single upsert, so concurrent callers get the same document, provided the filters are equality
+// conditions on fields with a unique index; without it, concurrent callers may insert duplicates.
+// The equality conditions of the filters are set on the inserted document, like in any upsert.
+// If the model implements the Validator interface, invalid models are rejected with the ErrValidation error.
+// It returns the found or created document, whether it was created and an error, if any.
+func (r *mongoRepository[T]) FindOrCreate(ctx context.Context, model T, filters ...FilterFunc) (_ T, created bool, err error) {
+	ctx, op := r.startOperation(ctx, "FindOrCreate")
+	defer func() { op.end(err) }()
+
+	var result T
+	if err := validate(&model); err != nil {
+		return result, false, errors.Join(ErrFailedToCreate, err)
+	}
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	doc, err := toBsonD(model)
+	if err != nil {
+		return result, false, errors.Join(ErrFailedToCreate, err)
+	}
+	// Set the ID explicitly to fetch the created document by it
+	id := interface{}(primitive.NewObjectID())
+	insert := make(bson.D, 0, len(doc)+1)
+	for _, e := range doc {
+		if e.Key == "_id" {
+			id = e.Value
+			continue
+		}
+		insert = append(insert, e)
+	}
+	insert = append(insert, bson.E{Key: "_id", Value: id})
+
+	// Return the document before the update, so no document means it was inserted
+	update := bson.D{{Key: "$setOnInsert", Value: insert}}
+	updateOpts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+	err = r.collection.FindOneAndUpdate(ctx, filter, update, updateOpts).Decode(&result)
+	switch {
+	case err == nil:
+		op.setDocumentCount(1)
+		return result, false, nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		// The document was inserted, so fetch it with the fields set from the filters
+		if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&result); err != nil {
+			return result, false, errors.Join(ErrFailedToCreate, err)
+		}
+		op.setDocumentCount(1)
+		return result, true, nil
+	case mongo.IsDuplicateKeyError(err):
+		// A concurrent caller inserted the document in the meantime
+		if err := r.collection.FindOne(ctx, filter).Decode(&result); err != nil {
+			return result, false, errors.Join(ErrFailedToCreate, err)
+		}
+		op.setDocumentCount(1)
+		return result, false, nil
+	case isDocumentTooLargeError(err):
+		return result, false, errors.Join(ErrFailedToCreate, ErrDocumentTooLarge, err)
+	default:
+		return result, false, errors.Join(ErrFailedToCreate, err)
 	}
-	return oid.Hex(), nil
 }
 
 // FindByID retrieves a document from the MongoDB collection by its ID.
 // It takes a context.Context and the ID of the document as parameters.
 // It returns the retrieved document of type T and an error, if any.
-func (r *mongoRepository[T]) FindByID(ctx context.Context, id string) (T, error) {
+func (r *mongoRepository[T]) FindByID(ctx context.Context, id string) (_ T, err error) {
+	ctx, op := r.startOperation(ctx, "FindByID")
+	defer func() { op.end(err) }()
+
 	var result T
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return result, errors.Join(ErrFailedToFindByID, ErrInvalidDocumentID, err)
 	}
 	filter := bson.M{"_id": objID}
-	if err := r.collection.FindOne(ctx, filter).Decode(&result); err != nil {
+	singleResult, err := retryRead(ctx, r.opts, func() (*mongo.SingleResult, error) {
+		singleResult := r.collection.FindOne(ctx, filter)
+		return singleResult, singleResult.Err()
+	})
+	if err == nil {
+		err = singleResult.Decode(&result)
+	}
+	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return result, errors.Join(ErrFailedToFindByID, ErrNotFound, err)
 		}
@@ -155,52 +641,268 @@ func (r *mongoRepository[T]) FindByID(ctx context.Context, id string) (T, error)
 
 // FindByIDs retrieves multiple documents from the MongoDB collection by their IDs.
 // It takes a context.Context and a slice of IDs as parameters.
+// Large ID sets are queried in chunks, fetched in parallel if WithConcurrency is set;
+// each document is returned once, even if its id is duplicated.
+// If none of the documents exist, it returns an error with the ErrNotFound error code,
+// or an empty slice if the repository is created with WithEmptyResultNil.
 // It returns a slice of documents of type T and an error, if any.
-func (r *mongoRepository[T]) FindByIDs(ctx context.Context, ids ...string) ([]T, error) {
-	// Convert string IDs to ObjectIDs
-	objIDs := make([]primitive.ObjectID, len(ids))
-	for i, id := range ids {
+func (r *mongoRepository[T]) FindByIDs(ctx context.Context, ids ...string) (_ []T, err error) {
+	ctx, op := r.startOperation(ctx, "FindByIDs")
+	defer func() { op.end(err) }()
+
+	// Convert string IDs to ObjectIDs, skipping duplicates
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	seen := make(map[primitive.ObjectID]struct{}, len(ids))
+	for _, id := range ids {
 		objID, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
 			return nil, errors.Join(ErrFailedToFindByIDs, ErrInvalidDocumentID, err)
 		}
-		objIDs[i] = objID
+		if _, ok := seen[objID]; ok {
+			continue
+		}
+		seen[objID] = struct{}{}
+		objIDs = append(objIDs, objID)
 	}
 
-	// Build the query filter
-	filter := bson.M{"_id": bson.M{"$in": objIDs}}
+	// Split the ids into chunks to keep the queries small
+	var chunks [][]primitive.ObjectID
+	for len(objIDs) > findByIDsChunkSize {
+		chunks = append(chunks, objIDs[:findByIDsChunkSize])
+		objIDs = objIDs[findByIDsChunkSize:]
+	}
+	chunks = append(chunks, objIDs)
 
-	// Find documents
-	cursor, err := r.collection.Find(ctx, filter, options.Find())
+	chunkResults, err := r.findChunks(ctx, chunks)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.Join(ErrFailedToFindByIDs, ErrNotFound, err)
 		}
 		return nil, errors.Join(ErrFailedToFindByIDs, err)
 	}
+
+	var results []T
+	for _, chunk := range chunkResults {
+		results = append(results, chunk...)
+	}
+	if len(results) == 0 {
+		if r.opts.emptyResultNil{
+			return []T{}, nil
+		}
+		return nil, errors.Join(ErrFailedToFindByIDs, ErrNotFound)
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// findByIDsChunkSize is the maximum number of ids queried by FindByIDs at once.
+const findByIDsChunkSize = 1000
+
+// findChunks fetches the documents of each chunk of ids, running up to the number of queries
+// configured by WithConcurrency in parallel. The results are returned in the order of the chunks.
+// The first error cancels the queries of the remaining chunks.
+func (r *mongoRepository[T]) findChunks(ctx context.Context, chunks [][]primitive.ObjectID) ([][]T, error) {
+	results := make([][]T, len(chunks))
+	if r.opts.concurrency <= 1 || len(chunks) == 1 {
+		for i, chunk := range chunks {
+			chunkResults, err := r.findChunk(ctx, chunk)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = chunkResults
+		}
+		return results, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, r.opts.concurrency)
+	for i, chunk := range chunks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, chunk []primitive.ObjectID) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			chunkResults, err := r.findChunk(ctx, chunk)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = chunkResults
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// findChunk fetches the documents with the given ids.
+func (r *mongoRepository[T]) findChunk(ctx context.Context, ids []primitive.ObjectID) ([]T, error) {
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Find(ctx, filter, options.Find())
+	})
+	if err != nil {
+		return nil, err
+	}
 	defer cursor.Close(ctx)
 
 	var results []T
 	for cursor.Next(ctx) {
 		var element T
 		if err := cursor.Decode(&element); err != nil {
-			return nil, errors.Join(ErrFailedToFindByIDs, err)
+			return nil, err
 		}
 		results = append(results, element)
 	}
 	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// FindByObjectIDsMap retrieves multiple documents from the MongoDB collection by their ObjectIDs.
+// It returns the documents keyed by their ObjectIDs, so callers which already have ObjectIDs,
+// e.g. from reference arrays, avoid hex conversions. Missing documents are absent from the map.
+// If none of the documents is found or no ids are given, it returns an error with the ErrNotFound error code.
+func (r *mongoRepository[T]) FindByObjectIDsMap(ctx context.Context, ids ...primitive.ObjectID) (_ map[primitive.ObjectID]T, err error) {
+	ctx, op := r.startOperation(ctx, "FindByObjectIDsMap")
+	defer func() { op.end(err) }()
+
+	results, err := r.findByObjectIDs(ctx, ids)
+	if err != nil {
 		return nil, errors.Join(ErrFailedToFindByIDs, err)
 	}
 	if len(results) == 0 {
 		return nil, errors.Join(ErrFailedToFindByIDs, ErrNotFound)
 	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// MissingIDsPolicy defines how FindByIDsOrdered handles the ids without a matching document.
+type MissingIDsPolicy int
+
+// Predefined missing ids policies
+const (
+	SkipMissingIDs     MissingIDsPolicy = iota // Leave the missing documents out of the results
+	FailOnMissingIDs                           // Return an error with the ErrNotFound error code
+	ZeroFillMissingIDs                         // Put the zero value of T in place of the missing documents
+)
+
+// FindByIDsOrdered retrieves multiple documents from the MongoDB collection by their IDs,
+// returned in the order of the given ids, e.g. to hydrate a list of ids coming from a search index.
+// The ids without a matching document are handled according to the missing policy;
+// a duplicated id results in the same document returned at each of its positions.
+// It returns a slice of documents of type T and an error, if any.
+func (r *mongoRepository[T]) FindByIDsOrdered(ctx context.Context, missing MissingIDsPolicy, ids ...string) (_ []T, err error) {
+	ctx, op := r.startOperation(ctx, "FindByIDsOrdered")
+	defer func() { op.end(err) }()
+
+	objIDs := make([]primitive.ObjectID, len(ids))
+	for i, id := range ids {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, errors.Join(ErrFailedToFindByIDs, ErrInvalidDocumentID, err)
+		}
+		objIDs[i] = objID
+	}
+
+	found, err := r.findByObjectIDs(ctx, objIDs)
+	if err != nil {
+		return nil, errors.Join(ErrFailedToFindByIDs, err)
+	}
+
+	results := make([]T, 0, len(objIDs))
+	var missingIDs []string
+	for i, objID := range objIDs {
+		element, ok := found[objID]
+		if !ok {
+			switch missing {
+			case FailOnMissingIDs:
+				missingIDs = append(missingIDs, ids[i])
+			case ZeroFillMissingIDs:
+				results = append(results, element)
+			}
+			continue
+		}
+		results = append(results, element)
+	}
+	if len(missingIDs) > 0 {
+		return nil, errors.Join(ErrFailedToFindByIDs, ErrNotFound, fmt.Errorf("missing ids: %s", strings.Join(missingIDs, ", ")))
+	}
+	op.setDocumentCount(int64(len(found)))
+	return results, nil
+}
+
+// findByObjectIDs retrieves the documents with the given ObjectIDs keyed by their ObjectIDs.
+func (r *mongoRepository[T]) findByObjectIDs(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]T, error) {
+	// Nothing to query, the server rejects the $in: null a nil slice of ids is encoded to
+	if len(ids) == 0 {
+		return map[primitive.ObjectID]T{}, nil
+	}
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Find(ctx, filter)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := make(map[primitive.ObjectID]T, len(ids))
+	for cursor.Next(ctx) {
+		var element T
+		if err := cursor.Decode(&element); err != nil {
+			return nil, err
+		}
+		id, ok := cursor.Current.Lookup("_id").ObjectIDOK()
+		if !ok {
+			return nil, ErrInvalidDocumentID
+		}
+		results[id] = element
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
 	return results, nil
 }
 
 // Update updates a document in the MongoDB collection with the specified ID.
 // It takes a context, ID string, and model as input parameters.
 // It returns the number of modified documents and an error, if any.
-func (r *mongoRepository[T]) Update(ctx context.Context, id string, model T) (int64, error) {
+// If the model implements the Validator interface, invalid models are rejected with the ErrValidation error.
+func (r *mongoRepository[T]) Update(ctx context.Context, id string, model T) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "Update")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return 0, errors.Join(ErrFailedToFindByID, ErrInvalidDocumentID, err)
@@ -211,11 +913,219 @@ func (r *mongoRepository[T]) Update(ctx context.Context, id string, model T) (in
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return 0, errors.Join(ErrFailedToUpdate, ErrNotFound, err)
 		}
+		if isDocumentTooLargeError(err) {
+			return 0, errors.Join(ErrFailedToUpdate, ErrDocumentTooLarge, err)
+		}
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+	if result.MatchedCount == 0 {
+		return 0, errors.Join(ErrFailedToUpdate, ErrNotFound)
+	}
+	op.setDocumentCount(result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
+
+// Replace replaces the whole document with the specified ID by the model, keeping the _id intact.
+// Unlike Update, which uses $set, fields absent from the model are removed from the stored document.
+// Any _id set in the model is ignored.
+// It returns the number of modified documents and an error, if any.
+// If the model implements the Validator interface, invalid models are rejected with the ErrValidation error.
+func (r *mongoRepository[T]) Replace(ctx context.Context, id string, model T) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "Replace")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return 0, errors.Join(ErrFailedToReplace, err)
+	}
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToReplace, ErrInvalidDocumentID, err)
+	}
+
+	// Remove the _id from the replacement document, since it's immutable
+	doc, err := toBsonD(model)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToReplace, err)
+	}
+	replacement := make(bson.D, 0, len(doc))
+	for _, e := range doc {
+		if e.Key != "_id" {
+			replacement = append(replacement, e)
+		}
+	}
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": objID}, replacement)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return 0, errors.Join(ErrFailedToReplace, ErrDuplicate, err)
+		}
+		if isDocumentTooLargeError(err) {
+			return 0, errors.Join(ErrFailedToReplace, ErrDocumentTooLarge, err)
+		}
+		return 0, errors.Join(ErrFailedToReplace, err)
+	}
+	if result.MatchedCount == 0 {
+		return 0, errors.Join(ErrFailedToReplace, ErrNotFound)
+	}
+	op.setDocumentCount(result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
+
+// UpdateAndReturn updates a document with the specified ID the same way as Update,
+// and returns the resulting document along with whether the update actually changed anything.
+// It is useful for idempotent PUT handlers.
+// The update is atomic: it's made by FindOneAndUpdate which returns the document as it was before the update,
+// then the resulting document is built by applying the model fields on top of it,
+// and the document is considered modified if any of the model fields differs from the stored value.
+// If the document does not exist, it returns an error with the ErrNotFound error code.
+// If the model implements the Validator interface, invalid models are rejected with the ErrValidation error.
+func (r *mongoRepository[T]) UpdateAndReturn(ctx context.Context, id string, model T) (doc T, modified bool, err error) {
+	ctx, op := r.startOperation(ctx, "UpdateAndReturn")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return doc, false, errors.Join(ErrFailedToUpdate, err)
+	}
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return doc, false, errors.Join(ErrFailedToUpdate, ErrInvalidDocumentID, err)
+	}
+	fields, err := toBsonD(model)
+	if err != nil {
+		return doc, false, errors.Join(ErrFailedToUpdate, err)
+	}
+
+	findOptions := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	before, err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": objID}, bson.M{"$set": fields}, findOptions).Raw()
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return doc, false, errors.Join(ErrFailedToUpdate, ErrNotFound, err)
+		}
+		return doc, false, errors.Join(ErrFailedToUpdate, err)
+	}
+
+	// Build the resulting document: the stored one with the model fields set on top of it
+	elements, err := before.Elements()
+	if err != nil {
+		return doc, false, errors.Join(ErrFailedToUpdate, err)
+	}
+	after := make(bson.D, 0, len(elements)+len(fields))
+	updated := make(map[string]bool, len(fields))
+	for _, e := range elements {
+		value := interface{}(e.Value())
+		for _, f := range fields {
+			if f.Key == e.Key() {
+				value = f.Value
+				updated[f.Key] = true
+				if !modified {
+					modified = !sameBsonValue(e.Value(), f.Value)
+				}
+				break
+			}
+		}
+		after = append(after, bson.E{Key: e.Key(), Value: value})
+	}
+	for _, f := range fields {
+		if !updated[f.Key] {
+			after = append(after, f)
+			modified = true
+		}
+	}
+
+	data, err := bson.Marshal(after)
+	if err != nil {
+		return doc, false, errors.Join(ErrFailedToUpdate, err)
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return doc, false, errors.Join(ErrFailedToUpdate, err)
+	}
+	if modified {
+		op.setDocumentCount(1)
+	}
+	return doc, modified, nil
+}
+
+// UpdateVersioned updates a document with the specified ID only if its version field equals expectedVersion,
+// and increments the version, implementing optimistic concurrency control to prevent lost updates.
+// The model fields are set the same way as in Update, except the version field, which is managed by the method.
+// If the document exists but has another version, it returns an error with the ErrVersionConflict error code.
+// If the model implements the Validator interface, invalid models are rejected with the ErrValidation error.
+// It returns the number of modified documents and an error, if any.
+func (r *mongoRepository[T]) UpdateVersioned(ctx context.Context, id string, model T, expectedVersion int64) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "UpdateVersioned")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, ErrInvalidDocumentID, err)
+	}
+	doc, err := toBsonD(model)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+
+	// The version can't be both set and incremented in the same update
+	fields := make(bson.D, 0, len(doc))
+	for _, e := range doc {
+		if e.Key != versionField {
+			fields = append(fields, e)
+		}
+	}
+
+	filter := bson.D{{Key: "_id", Value: objID}, {Key: versionField, Value: expectedVersion}}
+	update := bson.D{
+		{Key: "$set", Value: fields},
+		{Key: "$inc", Value: bson.M{versionField: 1}},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
 		return 0, errors.Join(ErrFailedToUpdate, err)
 	}
 	if result.MatchedCount == 0 {
+		// Tell a stale write from a missing document
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": objID})
+		if err != nil {
+			return 0, errors.Join(ErrFailedToUpdate, err)
+		}
+		if count > 0 {
+			return 0, errors.Join(ErrFailedToUpdate, ErrVersionConflict)
+		}
 		return 0, errors.Join(ErrFailedToUpdate, ErrNotFound)
 	}
+	op.setDocumentCount(result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
+
+// UpdateFields sets the given fields of the document with the given ID, leaving the other fields intact.
+// Dotted keys, e.g. Field("address", "city"), update a single field of an embedded document without replacing
+// the whole subdocument, while a key naming the embedded document itself replaces it as a whole.
+// If the document doesn't exist, it returns an error with the ErrNotFound error code.
+// It returns the number of modified documents and an error, if any.
+func (r *mongoRepository[T]) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "UpdateFields")
+	defer func() { op.end(err) }()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, ErrInvalidDocumentID, err)
+	}
+	result, err := r.collection.UpdateByID(ctx, objID, bson.M{"$set": fields})
+	if err != nil {
+		if isDocumentTooLargeError(err) {
+			return 0, errors.Join(ErrFailedToUpdate, ErrDocumentTooLarge, err)
+		}
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+	if result.MatchedCount == 0 {
+		return 0, errors.Join(ErrFailedToUpdate, ErrNotFound)
+	}
+	op.setDocumentCount(result.ModifiedCount)
 	return result.ModifiedCount, nil
 }
 
@@ -224,7 +1134,10 @@ func (r *mongoRepository[T]) Update(ctx context.Context, id string, model T) (in
 // The update fields specify the changes to be made to the documents.
 // The filter functions are used to build the filter for selecting the documents to be updated.
 // It returns the number of documents modified and an error if any.
-func (r *mongoRepository[T]) UpdateMany(ctx context.Context, update map[string]interface{}, filters ...FilterFunc) (int64, error) {
+func (r *mongoRepository[T]) UpdateMany(ctx context.Context, update map[string]interface{}, filters ...FilterFunc) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "UpdateMany")
+	defer func() { op.end(err) }()
+
 	// Build the filter
 	filter := bson.D{}
 	for _, f := range filters {
@@ -242,12 +1155,63 @@ func (r *mongoRepository[T]) UpdateMany(ctx context.Context, update map[string]i
 		}
 		return 0, errors.Join(ErrFailedToUpdateMany, err)
 	}
+	op.setDocumentCount(result.ModifiedCount)
 	return result.ModifiedCount, nil
 }
 
+// UpdateOneByFilter updates a single document matching the provided filters, unlike UpdateMany,
+// which updates all of them. If several documents match, the first one in the natural order is updated,
+// so use filters matching a single document, e.g. on a unique field, for a predictable result.
+// It takes a context.Context, a map of update fields, and optional filter functions as parameters.
+// If no documents match the filters, it returns an error with the ErrNotFound error code.
+// It returns the number of documents modified, zero if the document already had the values, and an error if any.
+func (r *mongoRepository[T]) UpdateOneByFilter(ctx context.Context, update map[string]interface{}, filters ...FilterFunc) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "UpdateOneByFilter")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": update})
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdate, err)
+	}
+	if result.MatchedCount == 0 {
+		return 0, errors.Join(ErrFailedToUpdate, ErrNotFound)
+	}
+	op.setDocumentCount(result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
+
+// CompareAndSet atomically sets the field of the document with the specified ID to newValue,
+// but only if the field currently equals the expected value.
+// It is useful for state machines to prevent invalid state transitions under concurrency.
+// It returns true if the value was set and false if the precondition did not hold, and an error, if any.
+func (r *mongoRepository[T]) CompareAndSet(ctx context.Context, id string, field string, expected, newValue interface{}) (_ bool, err error) {
+	ctx, op := r.startOperation(ctx, "CompareAndSet")
+	defer func() { op.end(err) }()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.Join(ErrFailedToUpdate, ErrInvalidDocumentID, err)
+	}
+	filter := bson.D{{Key: "_id", Value: objID}, {Key: field, Value: expected}}
+	update := bson.M{"$set": bson.M{field: newValue}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, errors.Join(ErrFailedToUpdate, err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
 // Delete deletes a document from the MongoDB collection based on the provided ID.
 // It returns the number of deleted documents and an error, if any.
-func (r *mongoRepository[T]) Delete(ctx context.Context, id string) (int64, error) {
+func (r *mongoRepository[T]) Delete(ctx context.Context, id string) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "Delete")
+	defer func() { op.end(err) }()
+
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return 0, errors.Join(ErrFailedToFindByID, ErrInvalidDocumentID, err)
@@ -262,12 +1226,42 @@ func (r *mongoRepository[T]) Delete(ctx context.Context, id string) (int64, erro
 	if result.DeletedCount == 0 {
 		return 0, errors.Join(ErrFailedToDelete, ErrNotFound)
 	}
+	op.setDocumentCount(result.DeletedCount)
+	return result.DeletedCount, nil
+}
+
+// DeleteByIDs deletes the documents with the given IDs from the MongoDB collection in a single operation.
+// All ids are validated before deleting anything: if any of them is malformed, nothing is deleted
+// and an error with the ErrInvalidDocumentID error code is returned.
+// Missing documents are ignored, so the number of deleted documents may be less than the number of ids.
+// It returns the number of deleted documents and an error, if any.
+func (r *mongoRepository[T]) DeleteByIDs(ctx context.Context, ids ...string) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "DeleteByIDs")
+	defer func() { op.end(err) }()
+
+	objIDs := make([]primitive.ObjectID, len(ids))
+	for i, id := range ids {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, errors.Join(ErrFailedToDeleteMany, ErrInvalidDocumentID, err)
+		}
+		objIDs[i] = objID
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": objIDs}})
+	if err != nil {
+		return 0, errors.Join(ErrFailedToDeleteMany, err)
+	}
+	op.setDocumentCount(result.DeletedCount)
 	return result.DeletedCount, nil
 }
 
 // DeleteMany deletes multiple documents from the MongoDB collection based on the provided filters.
 // It returns the number of deleted documents and an error, if any.
-func (r *mongoRepository[T]) DeleteMany(ctx context.Context, filters ...FilterFunc) (int64, error) {
+func (r *mongoRepository[T]) DeleteMany(ctx context.Context, filters ...FilterFunc) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "DeleteMany")
+	defer func() { op.end(err) }()
+
 	filter := bson.D{}
 	for _, f := range filters {
 		filter = f(filter)
@@ -279,25 +1273,114 @@ func (r *mongoRepository[T]) DeleteMany(ctx context.Context, filters ...FilterFu
 		}
 		return 0, errors.Join(ErrFailedToDeleteMany, err)
 	}
+	op.setDocumentCount(result.DeletedCount)
 	return result.DeletedCount, nil
 }
 
+// DeleteManyReturningIDs deletes multiple documents from the MongoDB collection based on the provided filters
+// and returns the IDs of the deleted documents, e.g. for cache invalidation.
+// It first fetches the IDs of the matching documents, projecting only the _id field, and then deletes
+// the documents with these IDs still matching the filters. The two steps are not atomic, so there is a small race window:
+// documents matching the filters inserted in between are not deleted, and documents deleted
+// or changed to no longer match the filters by another client in between are still returned. Run it within a transaction if that matters.
+// It returns the IDs of the deleted documents and an error, if any.
+func (r *mongoRepository[T]) DeleteManyReturningIDs(ctx context.Context, filters ...FilterFunc) (_ []string, err error) {
+	ctx, op := r.startOperation(ctx, "DeleteManyReturningIDs")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, errors.Join(ErrFailedToDeleteMany, err)
+	}
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, errors.Join(ErrFailedToDeleteMany, err)
+	}
+
+	ids := make([]string, len(docs))
+	objIDs := make([]primitive.ObjectID, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID.Hex()
+		objIDs[i] = doc.ID
+	}
+	if len(ids) == 0 {
+		op.setDocumentCount(int64(len(ids)))
+		return ids, nil
+	}
+
+	// Keep the filters, so documents no longer matching them in the meantime are not deleted
+	if _, err := r.collection.DeleteMany(ctx, appendCondition(filter, bson.E{Key: "_id", Value: bson.M{"$in": objIDs}})); err != nil {
+		return nil, errors.Join(ErrFailedToDeleteMany, err)
+	}
+	op.setDocumentCount(int64(len(ids)))
+	return ids, nil
+}
+
 // FindManyByFilter retrieves multiple documents from the collection based on the provided filters.
 // It allows skipping a certain number of documents and limiting the number of documents to be returned.
+// If limit is zero, the default limit set by WithDefaultLimit, 10 by default, is applied;
+// pass NoLimit to return all matching documents.
 // The filters are applied in the order they are passed.
-// If no documents match the filters, it returns an error with the ErrNotFound error code.
+// If no documents match the filters, it returns an error with the ErrNotFound error code,
+// or an empty slice if the repository is created with WithEmptyResultNil.
 // If an error occurs during the retrieval process, it returns an error with the ErrFailedToFindManyByFilter error code.
 // The function returns a slice of documents of type T and an error.
-func (r *mongoRepository[T]) FindManyByFilter(ctx context.Context, skip int64, limit int64, filters ...FilterFunc) ([]T, error) {
+func (r *mongoRepository[T]) FindManyByFilter(ctx context.Context, skip int64, limit int64, filters ...FilterFunc) (_ []T, err error) {
+	ctx, op := r.startOperation(ctx, "FindManyByFilter")
+	defer func() { op.end(err) }()
+
+	results, err := r.findMany(ctx, skip, limit, nil, filters)
+	if err != nil {
+		return nil, err
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// FindManyByFilterWithConfig works as FindManyByFilter, but applies the FindConfig(s) to the cursor,
+// e.g. BatchSize(100) and MaxTime(time.Second) for large result sets:
+//
+//	users, err := repo.FindManyByFilterWithConfig(ctx, 0, mongorepository.NoLimit,
+//		[]mongorepository.FindConfig{mongorepository.BatchSize(100), mongorepository.MaxTime(time.Second)},
+//		mongorepository.Eq("status", "active"))
+//
+// The function returns a slice of documents of type T and an error.
+func (r *mongoRepository[T]) FindManyByFilterWithConfig(ctx context.Context, skip, limit int64, config []FindConfig, filters ...FilterFunc) (_ []T, err error) {
+	ctx, op := r.startOperation(ctx, "FindManyByFilterWithConfig")
+	defer func() { op.end(err) }()
+
+	results, err := r.findMany(ctx, skip, limit, config, filters)
+	if err != nil {
+		return nil, err
+	}
+	op.setDocumentCount(int64(len(results)))
+	return results, nil
+}
+
+// findMany runs the query of FindManyByFilter, applying the FindConfig(s) to the find options.
+func (r *mongoRepository[T]) findMany(ctx context.Context, skip, limit int64, config []FindConfig, filters []FilterFunc) ([]T, error) {
 	filter := bson.D{}
 	for _, f := range filters {
 		filter = f(filter)
 	}
-	if limit == 0 {
-		limit = 10
+	limit, err := r.opts.limit(limit)
+	if err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+	findOptions := queryOptionsFromContext(ctx).findOptions(options.Find().SetSkip(skip).SetLimit(limit))
+	for _, c := range config {
+		c(findOptions)
 	}
-	findOptions := options.Find().SetSkip(skip).SetLimit(limit)
-	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Find(ctx, filter, findOptions)
+	})
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, errors.Join(ErrFailedToFindManyByFilter, ErrNotFound, err)
@@ -319,9 +1402,84 @@ func (r *mongoRepository[T]) FindManyByFilter(ctx context.Context, skip int64, l
 		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
 	}
 	if len(results) == 0 {
+		if r.opts.emptyResultNil{
+			return []T{}, nil
+		}
 		return nil, errors.Join(ErrFailedToFindManyByFilter, ErrNotFound)
 	}
+	return results, nil
+}
+
+// FindInto finds all documents matching the filters and decodes them into the caller-provided slice,
+// reusing its capacity, so that hot paths can pool the slices instead of allocating a new one per call.
+// The slice is truncated first, and it's left empty if an error occurs, so it never holds partial results.
+// Unlike FindManyByFilter, no limit is applied, and no matching documents result in an empty slice, not an error.
+// The query options carried by the context, e.g. WithSort, are applied.
+// The function returns an error, if any.
+func (r *mongoRepository[T]) FindInto(ctx context.Context, dst *[]T, filters ...FilterFunc) (err error) {
+	ctx, op := r.startOperation(ctx, "FindInto")
+	defer func() { op.end(err) }()
+
+	results := (*dst)[:0]
+	defer func() {
+		if err != nil {
+			results = results[:0]
+		}
+		*dst = results
+	}()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	findOptions := queryOptionsFromContext(ctx).findOptions(options.Find())
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Find(ctx, filter, findOptions)
+	})
+	if err != nil {
+		return errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+	defer cursor.Close(ctx)
 
+	var zero T
+	for cursor.Next(ctx) {
+		// Reset the reused element, so that no fields leak from the previous call
+		results = append(results, zero)
+		if err := cursor.Decode(&results[len(results)-1]); err != nil {
+			return errors.Join(ErrFailedToFindManyByFilter, err)
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+
+	op.setDocumentCount(int64(len(results)))
+	return nil
+}
+
+// FindDuplicatesOf computes the hash of the model with hashFn and returns the stored documents
+// having the same value in hashField. It supports "is this a duplicate before I insert" flows,
+// so hashField should be indexed for efficiency.
+// Unlike FindManyByFilter, it returns an empty slice without an error if there are no duplicates.
+func (r *mongoRepository[T]) FindDuplicatesOf(ctx context.Context, hashField string, model T, hashFn func(T) string) (_ []T, err error) {
+	ctx, op := r.startOperation(ctx, "FindDuplicatesOf")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{{Key: hashField, Value: hashFn(model)}}
+	cursor, err := retryRead(ctx, r.opts, func() (*mongo.Cursor, error) {
+		return r.collection.Find(ctx, filter)
+	})
+	if err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+	defer cursor.Close(ctx)
+
+	results := make([]T, 0)
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, errors.Join(ErrFailedToFindManyByFilter, err)
+	}
+	op.setDocumentCount(int64(len(results)))
 	return results, nil
 }
 
@@ -330,13 +1488,24 @@ func (r *mongoRepository[T]) FindManyByFilter(ctx context.Context, skip int64, l
 // The function returns the found document of type T and an error, if any.
 // If no document is found, it returns an error of type ErrNotFound.
 // If an error occurs during the find operation, it returns the error.
-func (r *mongoRepository[T]) FindOneByFilter(ctx context.Context, filters ...FilterFunc) (T, error) {
+func (r *mongoRepository[T]) FindOneByFilter(ctx context.Context, filters ...FilterFunc) (_ T, err error) {
+	ctx, op := r.startOperation(ctx, "FindOneByFilter")
+	defer func() { op.end(err) }()
+
 	filter := bson.D{}
 	for _, f := range filters {
 		filter = f(filter)
 	}
+	findOneOptions := queryOptionsFromContext(ctx).findOneOptions(options.FindOne())
 	var result T
-	if err := r.collection.FindOne(ctx, filter).Decode(&result); err != nil {
+	singleResult, err := retryRead(ctx, r.opts, func() (*mongo.SingleResult, error) {
+		singleResult := r.collection.FindOne(ctx, filter, findOneOptions)
+		return singleResult, singleResult.Err()
+	})
+	if err == nil {
+		err = singleResult.Decode(&result)
+	}
+	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return result, errors.Join(ErrFailedToFindOneByFilter, ErrNotFound, err)
 		}
@@ -349,22 +1518,82 @@ func (r *mongoRepository[T]) FindOneByFilter(ctx context.Context, filters ...Fil
 // It accepts one or more FilterFunc functions that modify the filter criteria.
 // The function returns true if a document exists and false otherwise.
 // If an error occurs during the find operation, it returns the error.
-func (r *mongoRepository[T]) Exists(ctx context.Context, filters ...FilterFunc) (bool, error) {
+func (r *mongoRepository[T]) Exists(ctx context.Context, filters ...FilterFunc) (_ bool, err error) {
+	ctx, op := r.startOperation(ctx, "Exists")
+	defer func() { op.end(err) }()
+
 	filter := bson.D{}
 	for _, f := range filters {
 		filter = f(filter)
 	}
-	count, err := r.collection.CountDocuments(ctx, filter)
+	count, err := retryRead(ctx, r.opts, func() (int64, error) {
+		return r.collection.CountDocuments(ctx, filter)
+	})
 	if err != nil {
 		return false, errors.Join(ErrFailedToFindOneByFilter, err)
 	}
 	return count > 0, nil
 }
 
+// WouldViolateUnique reports whether a document with the given field value already exists
+// among the documents matching the filters, so that e.g. "email is already taken" can be reported
+// before submitting a form. The filters define the scope of the unique constraint and should mirror
+// the index definition, e.g. Exists("deleted_at", false) for the CreateSoftDeleteAwareUniqueIndex index,
+// or the partial filter expression of a partial index. The collation carried by the context is applied,
+// so pass the index collation for case-insensitive indexes.
+// Note that the check is not atomic: a concurrent write may still violate the constraint.
+// The function returns a boolean and an error, if any.
+func (r *mongoRepository[T]) WouldViolateUnique(ctx context.Context, field string, value interface{}, filters ...FilterFunc) (_ bool, err error) {
+	ctx, op := r.startOperation(ctx, "WouldViolateUnique")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{{Key: field, Value: value}}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	countOptions := queryOptionsFromContext(ctx).countOptions(options.Count().SetLimit(1))
+	count, err := retryRead(ctx, r.opts, func() (int64, error) {
+		return r.collection.CountDocuments(ctx, filter, countOptions)
+	})
+	if err != nil {
+		return false, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
+	op.setDocumentCount(count)
+	return count > 0, nil
+}
+
 // Count returns the number of documents in the collection based on the provided filters.
 // It accepts one or more FilterFunc functions that modify the filter criteria.
 // The function returns the number of documents and an error, if any.
-func (r *mongoRepository[T]) Count(ctx context.Context, filters ...FilterFunc) (int64, error) {
+func (r *mongoRepository[T]) Count(ctx context.Context, filters ...FilterFunc) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "Count")
+	defer func() { op.end(err) }()
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	countOptions := queryOptionsFromContext(ctx).countOptions(options.Count())
+	count, err := retryRead(ctx, r.opts, func() (int64, error) {
+		return r.collection.CountDocuments(ctx, filter, countOptions)
+	})
+	if err != nil {
+		return 0, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
+	op.setDocumentCount(count)
+	return count, nil
+}
+
+// CountAffected returns the number of documents a write with the same filters, e.g. UpdateMany, UpdateManyOps
+// or DeleteMany, would affect, without modifying anything. It is a dry run of the write: pass it the filters
+// of a mass update to check how many documents it would touch before running it in production.
+// Note that the number of documents actually modified by an update may be lower, since documents
+// already having the new values are matched but not modified.
+// The function returns the number of matching documents and an error, if any.
+func (r *mongoRepository[T]) CountAffected(ctx context.Context, filters ...FilterFunc) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "CountAffected")
+	defer func() { op.end(err) }()
+
 	filter := bson.D{}
 	for _, f := range filters {
 		filter = f(filter)
@@ -373,5 +1602,133 @@ func (r *mongoRepository[T]) Count(ctx context.Context, filters ...FilterFunc) (
 	if err != nil {
 		return 0, errors.Join(ErrFailedToFindOneByFilter, err)
 	}
+	op.setDocumentCount(count)
+	return count, nil
+}
+
+// EstimatedCount returns an estimated number of documents in the whole collection.
+// It uses the collection metadata instead of scanning documents, so it is fast on huge collections,
+// but it can't take filters and may be inaccurate, e.g. after an unclean shutdown.
+// Use Count to get the exact number of documents matching the filters.
+// The function returns the number of documents and an error, if any.
+func (r *mongoRepository[T]) EstimatedCount(ctx context.Context) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "EstimatedCount")
+	defer func() { op.end(err) }()
+
+	count, err := retryRead(ctx, r.opts, func() (int64, error) {
+		return r.collection.EstimatedDocumentCount(ctx)
+	})
+	if err != nil {
+		return 0, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
 	return count, nil
 }
+
+// MaxTime returns the newest time value of the given date field among the documents matching the filters.
+// It uses a sort with limit 1 instead of an aggregation, so an index on the field makes it cheap.
+// Documents where the field is missing or is not a date are ignored.
+// If no documents match the filters, it returns an error with the ErrNotFound error code.
+func (r *mongoRepository[T]) MaxTime(ctx context.Context, field string, filters ...FilterFunc) (_ time.Time, err error) {
+	ctx, op := r.startOperation(ctx, "MaxTime")
+	defer func() { op.end(err) }()
+
+	return r.findBoundaryTime(ctx, field, -1, filters...)
+}
+
+// MinTime returns the oldest time value of the given date field among the documents matching the filters.
+// It uses a sort with limit 1 instead of an aggregation, so an index on the field makes it cheap.
+// Documents where the field is missing or is not a date are ignored.
+// If no documents match the filters, it returns an error with the ErrNotFound error code.
+func (r *mongoRepository[T]) MinTime(ctx context.Context, field string, filters ...FilterFunc) (_ time.Time, err error) {
+	ctx, op := r.startOperation(ctx, "MinTime")
+	defer func() { op.end(err) }()
+
+	return r.findBoundaryTime(ctx, field, 1, filters...)
+}
+
+// findBoundaryTime returns the first value of the date field in the given sort order.
+func (r *mongoRepository[T]) findBoundaryTime(ctx context.Context, field string, order int, filters ...FilterFunc) (time.Time, error) {
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+	filter = append(filter, bson.E{Key: field, Value: bson.M{"$type": "date"}})
+
+	findOptions := options.FindOne().
+		SetSort(bson.D{{Key: field, Value: order}}).
+		SetProjection(bson.M{field: 1})
+	raw, err := retryRead(ctx, r.opts, func() (bson.Raw, error) {
+		return r.collection.FindOne(ctx, filter, findOptions).Raw()
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return time.Time{}, errors.Join(ErrFailedToFindOneByFilter, ErrNotFound, err)
+		}
+		return time.Time{}, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
+
+	value, err := raw.LookupErr(strings.Split(field, ".")...)
+	if err != nil {
+		return time.Time{}, errors.Join(ErrFailedToFindOneByFilter, err)
+	}
+	result, ok := value.TimeOK()
+	if !ok {
+		return time.Time{}, errors.Join(ErrFailedToFindOneByFilter, ErrNotFound)
+	}
+	return result, nil
+}
+
+// toBsonD converts the model to a BSON document, preserving the order of the fields.
+func toBsonD(model interface{}) (bson.D, error) {
+	data, err := bson.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// lookupField returns the value of the field given in dot notation and whether the field exists.
+func lookupField(doc bson.D, path string) (interface{}, bool) {
+	key, rest, nested := strings.Cut(path, ".")
+	for _, e := range doc {
+		if e.Key != key {
+			continue
+		}
+		if !nested {
+			return e.Value, true
+		}
+		sub, ok := e.Value.(bson.D)
+		if !ok {
+			return nil, false
+		}
+		return lookupField(sub, rest)
+	}
+	return nil, false
+}
+
+// sameBsonValue reports whether the stored value is binary equal to the given value once marshaled,
+// the same way MongoDB detects no-op updates.
+func sameBsonValue(stored bson.RawValue, value interface{}) bool {
+	t, data, err := bson.MarshalValue(value)
+	if err != nil {
+		return false
+	}
+	return stored.Type == t && bytes.Equal(stored.Value, data)
+}
+
+// bsonObjectTooLargeCode is the error code returned by MongoDB when a document exceeds the maximum BSON size.
+const bsonObjectTooLargeCode = 10334
+
+// isDocumentTooLargeError reports whether the error is caused by a document exceeding the maximum BSON size,
+// either detected by the driver before sending it or by the server, e.g. after applying an update.
+func isDocumentTooLargeError(err error) bool {
+	if errors.Is(err, driver.ErrDocumentTooLarge) {
+		return true
+	}
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(bsonObjectTooLargeCode)
+}
@@ -0,0 +1,42 @@
+package mongorepository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Compile-time assertions that the concrete repository satisfies the public interfaces
+var (
+	_ Repository[struct{}]           = (*mongoRepository[struct{}])(nil)
+	_ SearchableRepository[struct{}] = (*mongoRepository[struct{}])(nil)
+)
+
+func TestIDString(t *testing.T) {
+	oid := primitive.NewObjectID()
+	uuid := primitive.Binary{Subtype: bson.TypeBinaryUUID, Data: []byte{
+		0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00,
+	}}
+
+	tests := []struct {
+		name string
+		id   interface{}
+		want string
+		ok   bool
+	}{
+		{"ObjectID", oid, oid.Hex(), true},
+		{"String", "user-42", "user-42", true},
+		{"UUID", uuid, "123e4567-e89b-12d3-a456-426614174000", true},
+		{"OtherBinary", primitive.Binary{Subtype: bson.TypeBinaryGeneric, Data: []byte{1}}, "", false},
+		{"Int", 42, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := idString(tt.id)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -2,12 +2,25 @@ package mongorepository_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	mongorepository "github.com/dmitrymomot/mongo-repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestRepository(t *testing.T) {
@@ -61,6 +74,13 @@ func TestRepository(t *testing.T) {
 		assert.Equal(t, int64(1), count)
 	})
 
+	// Test EstimatedCount
+	t.Run("EstimatedCount", func(t *testing.T) {
+		count, err := repo.EstimatedCount(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
 	// Test FindByID
 	t.Run("FindByID", func(t *testing.T) {
 		foundUser, err := repo.FindByID(context.Background(), id)
@@ -153,3 +173,1222 @@ func TestRepository(t *testing.T) {
 		assert.Equal(t, int64(0), updCount)
 	})
 }
+
+func TestUpdateOneByFilter(t *testing.T) {
+	type User struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Name   string             `bson:"name"`
+		Status string             `bson:"status"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	for _, name := range []string{"John", "Jane"} {
+		_, err := repo.Create(context.Background(), User{Name: name, Status: "pending"})
+		require.NoError(t, err)
+	}
+
+	// Both documents match, but only one is updated
+	modified, err := repo.UpdateOneByFilter(context.Background(), map[string]interface{}{"status": "active"},
+		mongorepository.Eq("status", "pending"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+
+	active, err := repo.Count(context.Background(), mongorepository.Eq("status", "active"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), active)
+	pending, err := repo.Count(context.Background(), mongorepository.Eq("status", "pending"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+
+	_, err = repo.UpdateOneByFilter(context.Background(), map[string]interface{}{"status": "active"},
+		mongorepository.Eq("name", "Alex"))
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+}
+
+func TestCompareAndSet(t *testing.T) {
+	type Order struct {
+		ID     primitive.ObjectID `bson:"_id,omitempty"`
+		Status string             `bson:"status"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Order](db, "orders")
+
+	id, err := repo.Create(context.Background(), Order{Status: "pending"})
+	require.NoError(t, err)
+
+	// Transition applies when the precondition holds
+	applied, err := repo.CompareAndSet(context.Background(), id, "status", "pending", "paid")
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	// The same transition fails since the status is not pending anymore
+	applied, err = repo.CompareAndSet(context.Background(), id, "status", "pending", "paid")
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	order, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "paid", order.Status)
+}
+
+func TestMinMaxTime(t *testing.T) {
+	type Event struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Kind      string             `bson:"kind"`
+		UpdatedAt time.Time          `bson:"updated_at"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Event](db, "events")
+
+	// Not found on empty collection
+	_, err := repo.MaxTime(context.Background(), "updated_at")
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for i := 0; i < 5; i++ {
+		_, err := repo.Create(context.Background(), Event{
+			Kind:      "click",
+			UpdatedAt: now.Add(-time.Duration(i) * time.Hour),
+		})
+		require.NoError(t, err)
+	}
+
+	maxTime, err := repo.MaxTime(context.Background(), "updated_at")
+	require.NoError(t, err)
+	assert.True(t, now.Equal(maxTime))
+
+	minTime, err := repo.MinTime(context.Background(), "updated_at", mongorepository.Eq("kind", "click"))
+	require.NoError(t, err)
+	assert.True(t, now.Add(-4*time.Hour).Equal(minTime))
+
+	_, err = repo.MinTime(context.Background(), "updated_at", mongorepository.Eq("kind", "view"))
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+}
+
+func TestFindDuplicatesOf(t *testing.T) {
+	type Document struct {
+		ID      primitive.ObjectID `bson:"_id,omitempty"`
+		Content string             `bson:"content"`
+		Hash    string             `bson:"hash"`
+	}
+
+	hashFn := func(d Document) string {
+		sum := sha256.Sum256([]byte(d.Content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Document](db, "documents")
+
+	stored := Document{Content: "hello world"}
+	stored.Hash = hashFn(stored)
+	id, err := repo.Create(context.Background(), stored)
+	require.NoError(t, err)
+
+	duplicates, err := repo.FindDuplicatesOf(context.Background(), "hash", Document{Content: "hello world"}, hashFn)
+	require.NoError(t, err)
+	require.Len(t, duplicates, 1)
+	assert.Equal(t, id, duplicates[0].ID.Hex())
+
+	duplicates, err = repo.FindDuplicatesOf(context.Background(), "hash", Document{Content: "unique"}, hashFn)
+	require.NoError(t, err)
+	assert.Empty(t, duplicates)
+}
+
+func TestQueryHook(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	var (
+		mu    sync.Mutex
+		calls []string
+		errs  []error
+	)
+	hook := func(op string, dur time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, op)
+		errs = append(errs, err)
+		assert.Positive(t, dur)
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithQueryHook(hook))
+
+	id, err := repo.Create(context.Background(), User{Name: "John Doe"})
+	require.NoError(t, err)
+
+	_, err = repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+
+	_, err = repo.FindByID(context.Background(), primitive.NewObjectID().Hex())
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"Create", "FindByID", "FindByID"}, calls)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.ErrorIs(t, errs[2], mongorepository.ErrNotFound)
+}
+
+func TestTracer(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("mongo-repository-test")
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithTracer(tracer))
+
+	for _, name := range []string{"John", "Jane"} {
+		_, err := repo.Create(context.Background(), User{Name: name})
+		require.NoError(t, err)
+	}
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	users, err := repo.FindManyByFilter(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	parent.End()
+
+	var span sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "mongo.FindManyByFilter" {
+			span = s
+		}
+	}
+	require.NotNil(t, span)
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Contains(t, span.Attributes(), attribute.String("db.mongodb.collection", "users"))
+	assert.Contains(t, span.Attributes(), attribute.Int64("db.mongodb.document_count", 2))
+	assert.Equal(t, codes.Unset, span.Status().Code)
+
+	// Errors are recorded on the span
+	_, err = repo.FindByID(context.Background(), "invalid")
+	require.Error(t, err)
+	spans := recorder.Ended()
+	last := spans[len(spans)-1]
+	assert.Equal(t, "mongo.FindByID", last.Name())
+	assert.Equal(t, codes.Error, last.Status().Code)
+}
+
+func TestReplace(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+		Bio  string             `bson:"bio,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	id, err := repo.Create(context.Background(), User{Name: "John Doe", Bio: "Software Engineer"})
+	require.NoError(t, err)
+	objID, err := primitive.ObjectIDFromHex(id)
+	require.NoError(t, err)
+
+	// The bio is absent in the replacement, so it must be removed
+	modified, err := repo.Replace(context.Background(), id, User{ID: primitive.NewObjectID(), Name: "John Replaced"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+
+	var raw bson.M
+	require.NoError(t, db.Collection("users").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&raw))
+	assert.Equal(t, objID, raw["_id"])
+	assert.Equal(t, "John Replaced", raw["name"])
+	assert.NotContains(t, raw, "bio")
+
+	// Replacing a non-existent document
+	_, err = repo.Replace(context.Background(), primitive.NewObjectID().Hex(), User{Name: "Nobody"})
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+}
+
+func TestUpdateAndReturn(t *testing.T) {
+	type User struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Name  string             `bson:"name"`
+		Email string             `bson:"email"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	user := User{Name: "John Doe", Email: "john@example.com"}
+	id, err := repo.Create(context.Background(), user)
+	require.NoError(t, err)
+
+	// Identical data doesn't modify the document, but it's still returned
+	doc, modified, err := repo.UpdateAndReturn(context.Background(), id, user)
+	require.NoError(t, err)
+	assert.False(t, modified)
+	assert.Equal(t, id, doc.ID.Hex())
+	assert.Equal(t, user.Name, doc.Name)
+	assert.Equal(t, user.Email, doc.Email)
+
+	// Changed data modifies the document
+	user.Name = "John Updated"
+	doc, modified, err = repo.UpdateAndReturn(context.Background(), id, user)
+	require.NoError(t, err)
+	assert.True(t, modified)
+	assert.Equal(t, "John Updated", doc.Name)
+
+	stored, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, stored, doc)
+
+	// Non-existent document
+	_, _, err = repo.UpdateAndReturn(context.Background(), primitive.NewObjectID().Hex(), user)
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+}
+
+// validatedUser is a model implementing the Validator interface.
+type validatedUser struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty"`
+	Email string             `bson:"email"`
+}
+
+func (u validatedUser) Validate() error {
+	if !strings.Contains(u.Email, "@") {
+		return errors.New("invalid email")
+	}
+	return nil
+}
+
+func TestValidation(t *testing.T) {
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[validatedUser](db, "users")
+
+	// Invalid document is not inserted
+	_, err := repo.Create(context.Background(), validatedUser{Email: "invalid"})
+	require.ErrorIs(t, err, mongorepository.ErrValidation)
+	require.ErrorIs(t, err, mongorepository.ErrFailedToCreate)
+
+	count, err := repo.Count(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// Valid document is inserted
+	id, err := repo.Create(context.Background(), validatedUser{Email: "john@example.com"})
+	require.NoError(t, err)
+
+	// Invalid update is rejected
+	_, err = repo.Update(context.Background(), id, validatedUser{Email: "invalid"})
+	require.ErrorIs(t, err, mongorepository.ErrValidation)
+
+	_, err = repo.Replace(context.Background(), id, validatedUser{Email: "invalid"})
+	require.ErrorIs(t, err, mongorepository.ErrValidation)
+
+	user, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "john@example.com", user.Email)
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	type Account struct {
+		ID      primitive.ObjectID `bson:"_id,omitempty"`
+		Balance int64              `bson:"balance"`
+		Version int64              `bson:"version"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Account](db, "accounts")
+
+	id, err := repo.Create(context.Background(), Account{Balance: 100})
+	require.NoError(t, err)
+
+	// Two clients read the same version
+	first, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	second, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+
+	// The first write succeeds and increments the version
+	first.Balance = 150
+	modified, err := repo.UpdateVersioned(context.Background(), id, first, first.Version)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+
+	// The second write is stale
+	second.Balance = 50
+	_, err = repo.UpdateVersioned(context.Background(), id, second, second.Version)
+	require.ErrorIs(t, err, mongorepository.ErrVersionConflict)
+
+	account, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), account.Balance)
+	assert.Equal(t, int64(1), account.Version)
+
+	// Missing document is not a conflict
+	_, err = repo.UpdateVersioned(context.Background(), primitive.NewObjectID().Hex(), first, 0)
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+}
+
+func TestFindByObjectIDsMap(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	users := []User{
+		{ID: primitive.NewObjectID(), Name: "John"},
+		{ID: primitive.NewObjectID(), Name: "Jane"},
+		{ID: primitive.NewObjectID(), Name: "Alex"},
+	}
+	for _, user := range users {
+		_, err := repo.Create(context.Background(), user)
+		require.NoError(t, err)
+	}
+
+	missing := primitive.NewObjectID()
+	found, err := repo.FindByObjectIDsMap(context.Background(), users[0].ID, users[2].ID, missing)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, users[0], found[users[0].ID])
+	assert.Equal(t, users[2], found[users[2].ID])
+	assert.NotContains(t, found, missing)
+
+	_, err = repo.FindByObjectIDsMap(context.Background(), missing)
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+
+	_, err = repo.FindByObjectIDsMap(context.Background())
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+}
+
+func TestFindInto(t *testing.T) {
+	type User struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Name  string             `bson:"name"`
+		Email string             `bson:"email,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	for _, name := range []string{"John", "Jane", "Alex"} {
+		_, err := repo.Create(context.Background(), User{Name: name})
+		require.NoError(t, err)
+	}
+
+	// The slice is truncated and its stale elements are not leaked into the results
+	users := []User{{Name: "stale", Email: "stale@example.com"}, {Name: "stale"}, {Name: "stale"}, {Name: "stale"}}
+	backing := &users[:cap(users)][0]
+	err := repo.FindInto(context.Background(), &users, mongorepository.Ne("name", "Jane"))
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "John", users[0].Name)
+	assert.Empty(t, users[0].Email)
+	assert.Equal(t, "Alex", users[1].Name)
+	assert.Same(t, backing, &users[0], "the capacity of the slice should be reused")
+
+	// No matching documents result in an empty slice
+	err = repo.FindInto(context.Background(), &users, mongorepository.Eq("name", "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	// Errors leave the slice empty
+	users = append(users, User{Name: "stale"})
+	err = repo.FindInto(context.Background(), &users, mongorepository.Where(bson.M{"$invalid": 1}))
+	require.ErrorIs(t, err, mongorepository.ErrFailedToFindManyByFilter)
+	assert.Empty(t, users)
+}
+
+func BenchmarkFindManyByFilter(b *testing.B) {
+	repo := mongorepository.NewMongoRepository[benchmarkUser](setupMongoDB(b), "users")
+	seedBenchmarkUsers(b, repo.Create)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.FindManyByFilter(context.Background(), 0, 100); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFindInto(b *testing.B) {
+	repo := mongorepository.NewMongoRepository[benchmarkUser](setupMongoDB(b), "users")
+	seedBenchmarkUsers(b, repo.Create)
+	users := make([]benchmarkUser, 0, 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := repo.FindInto(context.Background(), &users); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type benchmarkUser struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty"`
+	Name  string             `bson:"name"`
+	Email string             `bson:"email"`
+}
+
+// seedBenchmarkUsers creates 100 users with the given create function.
+func seedBenchmarkUsers(b *testing.B, create func(context.Context, benchmarkUser) (string, error)) {
+	for i := 0; i < 100; i++ {
+		_, err := create(context.Background(), benchmarkUser{
+			Name:  fmt.Sprintf("user %d", i),
+			Email: fmt.Sprintf("user%d@example.com", i),
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	type Product struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Name  string             `bson:"name"`
+		Price float64            `bson:"price"`
+	}
+
+	db := setupMongoDB(t)
+	require.NoError(t, db.CreateCollection(
+		context.Background(),
+		"products",
+		options.CreateCollection().SetValidator(bson.M{"$jsonSchema": bson.M{
+			"bsonType": "object",
+			"required": bson.A{"name", "price"},
+			"properties": bson.M{
+				"name":  bson.M{"bsonType": "string", "minLength": 1},
+				"price": bson.M{"bsonType": "double", "minimum": 0},
+			},
+		}}),
+	))
+	repo := mongorepository.NewMongoRepository[Product](db, "products")
+
+	// Valid candidate passes
+	require.NoError(t, repo.ValidateDocument(context.Background(), Product{Name: "pen", Price: 1.5}))
+
+	// Invalid candidates are rejected
+	err := repo.ValidateDocument(context.Background(), Product{Name: "", Price: 1.5})
+	require.ErrorIs(t, err, mongorepository.ErrValidation)
+	require.ErrorIs(t, err, mongorepository.ErrSchemaMismatch)
+
+	err = repo.ValidateDocument(context.Background(), Product{Name: "pen", Price: -1})
+	require.ErrorIs(t, err, mongorepository.ErrSchemaMismatch)
+
+	// Nothing is written
+	count, err := repo.Count(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// A collection without a validator accepts any document
+	other := mongorepository.NewMongoRepository[Product](db, "other_products")
+	require.NoError(t, other.ValidateDocument(context.Background(), Product{Price: -1}))
+}
+
+func TestDocumentTooLarge(t *testing.T) {
+	type Attachment struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Data []byte             `bson:"data"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Attachment](db, "attachments")
+
+	_, err := repo.Create(context.Background(), Attachment{Data: make([]byte, 17*1024*1024)})
+	require.ErrorIs(t, err, mongorepository.ErrDocumentTooLarge)
+	require.ErrorIs(t, err, mongorepository.ErrFailedToCreate)
+
+	// Documents below the limit are accepted
+	_, err = repo.Create(context.Background(), Attachment{Data: make([]byte, 1024)})
+	require.NoError(t, err)
+}
+
+func TestFindByIDsOrdered(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	var ids []string
+	for _, name := range []string{"John", "Jane", "Alex", "Kate"} {
+		id, err := repo.Create(context.Background(), User{Name: name})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	names := func(users []User) []string {
+		result := make([]string, 0, len(users))
+		for _, u := range users {
+			result = append(result, u.Name)
+		}
+		return result
+	}
+
+	shuffled := []string{ids[2], ids[0], ids[3], ids[1]}
+	missing := primitive.NewObjectID().Hex()
+
+	t.Run("Order", func(t *testing.T) {
+		users, err := repo.FindByIDsOrdered(context.Background(), mongorepository.SkipMissingIDs, shuffled...)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alex", "John", "Kate", "Jane"}, names(users))
+	})
+
+	t.Run("SkipMissingIDs", func(t *testing.T) {
+		users, err := repo.FindByIDsOrdered(context.Background(), mongorepository.SkipMissingIDs, ids[3], missing, ids[0])
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Kate", "John"}, names(users))
+	})
+
+	t.Run("ZeroFillMissingIDs", func(t *testing.T) {
+		users, err := repo.FindByIDsOrdered(context.Background(), mongorepository.ZeroFillMissingIDs, ids[3], missing, ids[0])
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Kate", "", "John"}, names(users))
+		assert.True(t, users[1].ID.IsZero())
+	})
+
+	t.Run("FailOnMissingIDs", func(t *testing.T) {
+		_, err := repo.FindByIDsOrdered(context.Background(), mongorepository.FailOnMissingIDs, ids[3], missing, ids[0])
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+		assert.Contains(t, err.Error(), missing)
+	})
+}
+
+func TestWouldViolateUnique(t *testing.T) {
+	type User struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Email     string             `bson:"email"`
+		DeletedAt *time.Time         `bson:"deleted_at,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	require.NoError(t, repo.CreateSoftDeleteAwareUniqueIndex(context.Background(), "email", "deleted_at"))
+
+	email := "john@example.com"
+	active := mongorepository.Exists("deleted_at", false)
+
+	taken, err := repo.WouldViolateUnique(context.Background(), "email", email, active)
+	require.NoError(t, err)
+	assert.False(t, taken)
+
+	id, err := repo.Create(context.Background(), User{Email: email})
+	require.NoError(t, err)
+
+	taken, err = repo.WouldViolateUnique(context.Background(), "email", email, active)
+	require.NoError(t, err)
+	assert.True(t, taken)
+
+	// Soft-delete the document
+	objID, err := primitive.ObjectIDFromHex(id)
+	require.NoError(t, err)
+	_, err = repo.UpdateMany(
+		context.Background(),
+		map[string]interface{}{"deleted_at": time.Now()},
+		mongorepository.Eq("_id", objID),
+	)
+	require.NoError(t, err)
+
+	taken, err = repo.WouldViolateUnique(context.Background(), "email", email, active)
+	require.NoError(t, err)
+	assert.False(t, taken)
+
+	// The actual constraint agrees
+	_, err = repo.Create(context.Background(), User{Email: email})
+	require.NoError(t, err)
+}
+
+func TestCreateWithPresetID(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id"`
+		Name string             `bson:"name"`
+	}
+	type Account struct {
+		ID   string `bson:"_id"`
+		Name string `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+
+	t.Run("ObjectID", func(t *testing.T) {
+		repo := mongorepository.NewMongoRepository[User](db, "users")
+		preset := primitive.NewObjectID()
+		id, err := repo.Create(context.Background(), User{ID: preset, Name: "John"})
+		require.NoError(t, err)
+		assert.Equal(t, preset.Hex(), id)
+
+		user, err := repo.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "John", user.Name)
+	})
+
+	t.Run("String", func(t *testing.T) {
+		repo := mongorepository.NewMongoRepository[Account](db, "accounts")
+		id, err := repo.Create(context.Background(), Account{ID: "123e4567-e89b-12d3-a456-426614174000", Name: "Jane"})
+		require.NoError(t, err)
+		assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", id)
+
+		_, err = repo.Create(context.Background(), Account{ID: id, Name: "Jane"})
+		require.ErrorIs(t, err, mongorepository.ErrDuplicate)
+	})
+}
+
+func TestCreateUnacknowledged(t *testing.T) {
+	type Event struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Event](db, "events")
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, repo.CreateUnacknowledged(context.Background(), Event{Name: fmt.Sprintf("event%d", i)}))
+	}
+
+	// The writes land eventually
+	require.Eventually(t, func() bool {
+		count, err := repo.Count(context.Background())
+		return err == nil && count == 10
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The other methods still wait for the acknowledgement
+	_, err := repo.Create(context.Background(), Event{Name: "acknowledged"})
+	require.NoError(t, err)
+}
+
+func TestCreateIdempotent(t *testing.T) {
+	type Payment struct {
+		ID             primitive.ObjectID `bson:"_id,omitempty"`
+		Amount         int64              `bson:"amount"`
+		IdempotencyKey string             `bson:"idempotency_key,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Payment](db, "payments")
+	require.NoError(t, repo.EnsureIndex(context.Background(), "idempotency_key", mongorepository.Unique(true)))
+
+	id, created, err := repo.CreateIdempotent(context.Background(), Payment{Amount: 100}, "request-1")
+	require.NoError(t, err)
+	assert.True(t, created)
+	require.NotEmpty(t, id)
+
+	// A retry with the same key returns the existing document
+	retryID, created, err := repo.CreateIdempotent(context.Background(), Payment{Amount: 200}, "request-1")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, id, retryID)
+
+	count, err := repo.Count(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	payment, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), payment.Amount)
+	assert.Equal(t, "request-1", payment.IdempotencyKey)
+
+	// Another key creates another document
+	otherID, created, err := repo.CreateIdempotent(context.Background(), Payment{Amount: 300}, "request-2")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEqual(t, id, otherID)
+}
+
+func TestFindOrCreate(t *testing.T) {
+	type User struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Email string             `bson:"email"`
+		Name  string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	require.NoError(t, repo.EnsureIndex(context.Background(), "email", mongorepository.Unique(true)))
+
+	user, created, err := repo.FindOrCreate(context.Background(), User{Email: "john@example.com", Name: "John"},
+		mongorepository.Eq("email", "john@example.com"))
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.False(t, user.ID.IsZero())
+	assert.Equal(t, "John", user.Name)
+
+	// The existing document is returned untouched
+	existing, created, err := repo.FindOrCreate(context.Background(), User{Email: "john@example.com", Name: "Johnny"},
+		mongorepository.Eq("email", "john@example.com"))
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, user, existing)
+
+	t.Run("Concurrent", func(t *testing.T) {
+		const callers = 10
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			ids     = make(map[primitive.ObjectID]struct{})
+			creates int
+		)
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				user, created, err := repo.FindOrCreate(context.Background(), User{Email: "jane@example.com", Name: fmt.Sprintf("Jane %d", i)},
+					mongorepository.Eq("email", "jane@example.com"))
+				assert.NoError(t, err)
+				mu.Lock()
+				defer mu.Unlock()
+				ids[user.ID] = struct{}{}
+				if created {
+					creates++
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Len(t, ids, 1)
+		assert.Equal(t, 1, creates)
+		count, err := repo.Count(context.Background(), mongorepository.Eq("email", "jane@example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestFindByIDsConcurrency(t *testing.T) {
+	type Item struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Index int                `bson:"index"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[Item](db, "items", mongorepository.WithConcurrency(3))
+
+	// Enough items for several chunks
+	const total = 4500
+	ids := make([]string, 0, total)
+	ops := make([]mongorepository.WriteOp, 0, total)
+	for i := 0; i < total; i++ {
+		item := Item{ID: primitive.NewObjectID(), Index: i}
+		ids = append(ids, item.ID.Hex())
+		ops = append(ops, mongorepository.InsertOp(item))
+	}
+	_, err := repo.BulkWrite(context.Background(), ops, false)
+	require.NoError(t, err)
+
+	// Duplicated and missing ids don't affect the result
+	requested := append(append([]string{}, ids...), ids[0], ids[total-1], primitive.NewObjectID().Hex())
+	items, err := repo.FindByIDs(context.Background(), requested...)
+	require.NoError(t, err)
+	require.Len(t, items, total)
+
+	seen := make(map[int]bool, total)
+	for _, item := range items {
+		assert.False(t, seen[item.Index], "item %d returned twice", item.Index)
+		seen[item.Index] = true
+	}
+	assert.Len(t, seen, total)
+
+	// Errors cancel the remaining chunks
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = repo.FindByIDs(ctx, ids...)
+	require.ErrorIs(t, err, mongorepository.ErrFailedToFindByIDs)
+}
+
+func TestDeleteByIDs(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	var ids []string
+	for _, name := range []string{"John", "Jane", "Alex", "Kate", "Mike"} {
+		id, err := repo.Create(context.Background(), User{Name: name})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	// A malformed id prevents the deletion of the valid ones
+	_, err := repo.DeleteByIDs(context.Background(), ids[0], "invalid")
+	require.ErrorIs(t, err, mongorepository.ErrInvalidDocumentID)
+	count, err := repo.Count(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+
+	deleted, err := repo.DeleteByIDs(context.Background(), ids[0], ids[2], ids[4])
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	count, err = repo.Count(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Already deleted documents are ignored
+	deleted, err = repo.DeleteByIDs(context.Background(), ids[0], ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestDeleteManyReturningIDs(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+		Age  int                `bson:"age"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	var expected []string
+	for i, name := range []string{"John", "Jane", "Alex", "Kate", "Mike"} {
+		id, err := repo.Create(context.Background(), User{Name: name, Age: 20 + i*5})
+		require.NoError(t, err)
+		if 20+i*5 >= 30 {
+			expected = append(expected, id)
+		}
+	}
+
+	t.Run("Delete", func(t *testing.T) {
+		ids, err := repo.DeleteManyReturningIDs(context.Background(), mongorepository.Gte("age", 30))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, expected, ids)
+
+		for _, id := range ids {
+			_, err := repo.FindByID(context.Background(), id)
+			require.ErrorIs(t, err, mongorepository.ErrNotFound)
+		}
+		count, err := repo.Count(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("NoMatches", func(t *testing.T) {
+		ids, err := repo.DeleteManyReturningIDs(context.Background(), mongorepository.Gte("age", 30))
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+}
+
+func TestForDatabase(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	tenantDB := db.Client().Database("test_db_tenant")
+	t.Cleanup(func() {
+		if err := tenantDB.Drop(context.Background()); err != nil {
+			t.Errorf("Failed to drop database: %v", err)
+		}
+	})
+
+	var ops []string
+	hook := func(op string, _ time.Duration, _ error) { ops = append(ops, op) }
+	repo := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithQueryHook(hook))
+	tenantRepo := repo.ForDatabase(tenantDB)
+
+	id, err := repo.Create(context.Background(), User{Name: "John"})
+	require.NoError(t, err)
+	tenantID, err := tenantRepo.Create(context.Background(), User{Name: "Jane"})
+	require.NoError(t, err)
+
+	// Documents don't leak across the databases
+	_, err = tenantRepo.FindByID(context.Background(), id)
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+	_, err = repo.FindByID(context.Background(), tenantID)
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+
+	count, err := tenantRepo.Count(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// The collection name and the options are preserved
+	n, err := tenantDB.Collection("users").CountDocuments(context.Background(), bson.M{"name": "Jane"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Contains(t, ops, "Count")
+}
+
+func TestEmptyResultNil(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	strict := mongorepository.NewMongoRepository[User](db, "users")
+	lenient := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithEmptyResultNil())
+	require.NoError(t, strict.CreateFullTextIndex(context.Background(), map[string]int32{"name": 1}, "english"))
+
+	_, err := strict.Create(context.Background(), User{Name: "John"})
+	require.NoError(t, err)
+	missingID := primitive.NewObjectID().Hex()
+
+	t.Run("Default", func(t *testing.T) {
+		_, err := strict.FindManyByFilter(context.Background(), 0, 0, mongorepository.Eq("name", "Jane"))
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+		_, err = strict.FindByIDs(context.Background(), missingID)
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+		_, err = strict.FindByIDs(context.Background())
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+		_, err = strict.Search(context.Background(), 0, 10, "Jane")
+		require.ErrorIs(t, err, mongorepository.ErrNotFound)
+	})
+
+	t.Run("EmptyResultNil", func(t *testing.T) {
+		users, err := lenient.FindManyByFilter(context.Background(), 0, 0, mongorepository.Eq("name", "Jane"))
+		require.NoError(t, err)
+		assert.Empty(t, users)
+		users, err = lenient.FindByIDs(context.Background(), missingID)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+		users, err = lenient.FindByIDs(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, users)
+		users, err = lenient.Search(context.Background(), 0, 10, "Jane")
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		// Found documents are returned as usual
+		users, err = lenient.FindManyByFilter(context.Background(), 0, 0, mongorepository.Eq("name", "John"))
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+	})
+
+	t.Run("EmptyResultOK", func(t *testing.T) {
+		alias := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithEmptyResultOK())
+		users, err := alias.FindManyByFilter(context.Background(), 0, 0, mongorepository.Eq("name", "Jane"))
+		require.NoError(t, err)
+		assert.Empty(t, users)
+	})
+}
+
+func TestDefaultLimit(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	for i := 0; i < 25; i++ {
+		_, err := repo.Create(context.Background(), User{Name: fmt.Sprintf("user%d", i)})
+		require.NoError(t, err)
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		users, err := repo.FindManyByFilter(context.Background(), 0, 0)
+		require.NoError(t, err)
+		assert.Len(t, users, 10)
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		custom := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithDefaultLimit(20))
+		users, err := custom.FindManyByFilter(context.Background(), 0, 0)
+		require.NoError(t, err)
+		assert.Len(t, users, 20)
+
+		// An explicit limit takes precedence
+		users, err = custom.FindManyByFilter(context.Background(), 0, 5)
+		require.NoError(t, err)
+		assert.Len(t, users, 5)
+	})
+
+	t.Run("Unbounded", func(t *testing.T) {
+		users, err := repo.FindManyByFilter(context.Background(), 0, mongorepository.NoLimit)
+		require.NoError(t, err)
+		assert.Len(t, users, 25)
+
+		unbounded := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithDefaultLimit(mongorepository.NoLimit))
+		users, err = unbounded.FindManyByFilter(context.Background(), 0, 0)
+		require.NoError(t, err)
+		assert.Len(t, users, 25)
+
+		items, total, err := unbounded.FindPageFaceted(context.Background(), 5, 0)
+		require.NoError(t, err)
+		assert.Len(t, items, 20)
+		assert.Equal(t, int64(25), total)
+	})
+}
+
+func TestMaxLimit(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+	for i := 0; i < 25; i++ {
+		_, err := repo.Create(context.Background(), User{Name: fmt.Sprintf("user%d", i)})
+		require.NoError(t, err)
+	}
+
+	t.Run("Clamp", func(t *testing.T) {
+		capped := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithMaxLimit(15, false))
+		users, err := capped.FindManyByFilter(context.Background(), 0, 1000)
+		require.NoError(t, err)
+		assert.Len(t, users, 15)
+
+		users, err = capped.FindManyByFilter(context.Background(), 0, mongorepository.NoLimit)
+		require.NoError(t, err)
+		assert.Len(t, users, 15)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		strict := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithMaxLimit(15, true))
+		_, err := strict.FindManyByFilter(context.Background(), 0, 1000)
+		require.ErrorIs(t, err, mongorepository.ErrLimitExceeded)
+
+		users, err := strict.FindManyByFilter(context.Background(), 0, 15)
+		require.NoError(t, err)
+		assert.Len(t, users, 15)
+	})
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	type User struct {
+		ID   primitive.ObjectID `bson:"_id,omitempty"`
+		Name string             `bson:"name"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithDefaultTimeout(time.Nanosecond))
+
+	// A context without a deadline gets the tiny default timeout
+	_, err := repo.Count(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// A context with a deadline is used as is
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = repo.Create(ctx, User{Name: "John"})
+	require.NoError(t, err)
+
+	// Streaming operations iterate until the cursor is exhausted, without the default timeout
+	var joined int
+	err = repo.JoinStream(context.Background(), "orders", "_id", "user_id", "orders", func(bson.M) error {
+		joined++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, joined)
+
+	// Cancellation of the incoming context still propagates
+	repo = mongorepository.NewMongoRepository[User](db, "users", mongorepository.WithDefaultTimeout(time.Minute))
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = repo.Count(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSchemaless(t *testing.T) {
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[bson.M](db, "events")
+
+	id, err := repo.Create(context.Background(), bson.M{"type": "signup", "attempts": 1, "meta": bson.M{"source": "web"}})
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	t.Run("FindByID", func(t *testing.T) {
+		doc, err := repo.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		oid, ok := doc["_id"].(primitive.ObjectID)
+		require.True(t, ok)
+		assert.Equal(t, id, oid.Hex())
+		assert.Equal(t, "signup", doc["type"])
+		assert.EqualValues(t, 1, doc["attempts"])
+		assert.Equal(t, bson.M{"source": "web"}, doc["meta"])
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		// Only the given fields are set
+		modified, err := repo.Update(context.Background(), id, bson.M{"attempts": 2})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), modified)
+
+		// A fetched document, including its _id, can be written back
+		doc, err := repo.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "signup", doc["type"])
+		assert.EqualValues(t, 2, doc["attempts"])
+		doc["verified"] = true
+		_, err = repo.Update(context.Background(), id, doc)
+		require.NoError(t, err)
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		_, err := repo.Replace(context.Background(), id, bson.M{"type": "login"})
+		require.NoError(t, err)
+
+		doc, err := repo.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "login", doc["type"])
+		assert.NotContains(t, doc, "attempts")
+	})
+
+	t.Run("FindManyByFilter", func(t *testing.T) {
+		_, err := repo.Create(context.Background(), bson.M{"type": "login", "_id": primitive.NewObjectID()})
+		require.NoError(t, err)
+
+		docs, err := repo.FindManyByFilter(context.Background(), 0, 0, mongorepository.Eq("type", "login"))
+		require.NoError(t, err)
+		assert.Len(t, docs, 2)
+
+		doc, err := repo.FindOneByFilter(context.Background(), mongorepository.Exists("verified", false))
+		require.NoError(t, err)
+		assert.Equal(t, "login", doc["type"])
+	})
+}
+
+func TestUpdateFields(t *testing.T) {
+	type Address struct {
+		City string `bson:"city"`
+		Zip  string `bson:"zip"`
+	}
+	type User struct {
+		ID      primitive.ObjectID `bson:"_id,omitempty"`
+		Name    string             `bson:"name"`
+		Address Address            `bson:"address"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	id, err := repo.Create(context.Background(), User{Name: "John", Address: Address{City: "Berlin", Zip: "10115"}})
+	require.NoError(t, err)
+
+	// Only the nested city is updated
+	modified, err := repo.UpdateFields(context.Background(), id, map[string]interface{}{
+		mongorepository.Field("address", "city"): "Munich",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+
+	user, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "John", user.Name)
+	assert.Equal(t, Address{City: "Munich", Zip: "10115"}, user.Address)
+
+	// Querying by the nested field
+	found, err := repo.FindOneByFilter(context.Background(), mongorepository.NestedEq([]string{"address", "city"}, "Munich"))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	// Missing document
+	_, err = repo.UpdateFields(context.Background(), primitive.NewObjectID().Hex(), map[string]interface{}{"name": "Jane"})
+	require.ErrorIs(t, err, mongorepository.ErrNotFound)
+}
@@ -0,0 +1,32 @@
+package mongorepository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// retryRead calls the read operation until it succeeds, fails with a non-transient error,
+// or the number of attempts configured by WithRetry is exhausted.
+// Transient errors are timeouts and network errors. The delay between attempts doubles after every attempt.
+// It must be used for idempotent read operations only: writes rely on the driver's retryable writes.
+func retryRead[R any](ctx context.Context, opts repositoryOptions, fn func() (R, error)) (R, error) {
+	result, err := fn()
+	backoff := opts.retryBackoff
+	for attempt := 1; attempt < opts.retryAttempts && err != nil && isTransientError(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		result, err = fn()
+	}
+	return result, err
+}
+
+// isTransientError reports whether the error is a timeout or a network error worth retrying.
+func isTransientError(err error) bool {
+	return mongo.IsTimeout(err) || mongo.IsNetworkError(err)
+}
@@ -0,0 +1,74 @@
+package mongorepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestRetryRead(t *testing.T) {
+	networkErr := mongo.CommandError{Code: 6, Message: "host unreachable", Labels: []string{"NetworkError"}}
+
+	opts := repositoryOptions{}
+	WithRetry(3, time.Millisecond)(&opts)
+
+	t.Run("SucceedsAfterTransientErrors", func(t *testing.T) {
+		calls := 0
+		result, err := retryRead(context.Background(), opts, func() (int, error) {
+			calls++
+			if calls < 3 {
+				return 0, networkErr
+			}
+			return 42, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 42, result)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("GivesUpAfterMaxAttempts", func(t *testing.T) {
+		calls := 0
+		_, err := retryRead(context.Background(), opts, func() (int, error) {
+			calls++
+			return 0, networkErr
+		})
+		require.ErrorAs(t, err, &mongo.CommandError{})
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("DoesNotRetryPermanentErrors", func(t *testing.T) {
+		calls := 0
+		_, err := retryRead(context.Background(), opts, func() (int, error) {
+			calls++
+			return 0, mongo.ErrNoDocuments
+		})
+		require.ErrorIs(t, err, mongo.ErrNoDocuments)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		calls := 0
+		_, err := retryRead(context.Background(), repositoryOptions{}, func() (int, error) {
+			calls++
+			return 0, networkErr
+		})
+		require.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("StopsOnContextCancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		_, err := retryRead(ctx, opts, func() (int, error) {
+			calls++
+			return 0, networkErr
+		})
+		require.ErrorAs(t, err, &mongo.CommandError{})
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,101 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// UnitOfWork runs operations of several repositories as a single multi-document transaction.
+// Transactions require MongoDB to be deployed as a replica set or a sharded cluster.
+type UnitOfWork struct {
+	client *mongo.Client
+}
+
+// NewUnitOfWork creates a new instance of the UnitOfWork struct.
+// It takes the mongo.Client shared by the repositories taking part in the unit of work.
+func NewUnitOfWork(client *mongo.Client) *UnitOfWork {
+	return &UnitOfWork{client: client}
+}
+
+// Do executes the given function inside a transaction.
+// The function receives a session-bound context: repository methods called with this context
+// run on the session and become part of the transaction.
+// If the function returns an error, all changes made within it are rolled back in every collection,
+// otherwise the transaction is committed as a whole.
+// The function may be called more than once if the transaction hits a transient error,
+// so it must be safe to retry.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := u.client.StartSession()
+	if err != nil {
+		return errors.Join(ErrFailedToExecuteTransaction, err)
+	}
+	defer session.EndSession(ctx)
+
+	if _, err := session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	}); err != nil {
+		return errors.Join(ErrFailedToExecuteTransaction, err)
+	}
+	return nil
+}
+
+// SessionOption configures the session started by UnitOfWork.WithSession.
+type SessionOption func(*options.SessionOptions)
+
+// WithCausalConsistency enables causal consistency of the session, so every operation in the session
+// observes the results of the previous ones, e.g. a read sees the preceding write even if it's served by a secondary.
+// WithSession enables it by default, so it's only needed to override a preceding WithoutCausalConsistency.
+// Causal consistency is only guaranteed with the majority read and write concerns,
+// so combine it with WithMajorityConcerns unless the collections already use them.
+func WithCausalConsistency() SessionOption {
+	return func(opts *options.SessionOptions) {
+		opts.SetCausalConsistency(true)
+	}
+}
+
+// WithoutCausalConsistency disables causal consistency of the session, e.g. for independent reads
+// which don't need to observe the preceding writes.
+func WithoutCausalConsistency() SessionOption {
+	return func(opts *options.SessionOptions) {
+		opts.SetCausalConsistency(false)
+	}
+}
+
+// WithMajorityConcerns sets the majority read and write concerns as the session defaults,
+// so the operations only read and acknowledge writes persisted by the majority of the replica set members.
+func WithMajorityConcerns() SessionOption {
+	return func(opts *options.SessionOptions) {
+		opts.SetDefaultReadConcern(readconcern.Majority())
+		opts.SetDefaultWriteConcern(writeconcern.Majority())
+	}
+}
+
+// WithSession executes the given function within a session, without a transaction.
+// The function receives a session-bound context: repository methods called with this context run on the session.
+// The session is started with causal consistency enabled, so the operations read their own writes,
+// e.g. a read-then-write or a write-then-read sequence across several repositories; pass WithoutCausalConsistency
+// to disable it. The read and write concerns of the client are kept, unless WithMajorityConcerns is passed,
+// which causal consistency needs to be guaranteed.
+// Unlike Do, the changes are not rolled back if the function returns an error, and the function is never retried.
+// It returns the error returned by the function, if any.
+func (u *UnitOfWork) WithSession(ctx context.Context, fn func(ctx context.Context) error, opts ...SessionOption) error {
+	sessionOpts := options.Session().SetCausalConsistency(true)
+	for _, opt := range opts {
+		opt(sessionOpts)
+	}
+
+	session, err := u.client.StartSession(sessionOpts)
+	if err != nil {
+		return errors.Join(ErrFailedToStartSession, err)
+	}
+	defer session.EndSession(ctx)
+
+	return mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		return fn(sessCtx)
+	})
+}
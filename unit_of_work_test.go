@@ -0,0 +1,127 @@
+package mongorepository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	type Order struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Item  string             `bson:"item"`
+		Total int64              `bson:"total"`
+	}
+	type Inventory struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Item  string             `bson:"item"`
+		Stock int64              `bson:"stock"`
+	}
+
+	db := setupMongoDB(t)
+	requireReplicaSet(t, db)
+
+	orders := mongorepository.NewMongoRepository[Order](db, "orders")
+	inventory := mongorepository.NewMongoRepository[Inventory](db, "inventory")
+	uow := mongorepository.NewUnitOfWork(db.Client())
+
+	itemID, err := inventory.Create(context.Background(), Inventory{Item: "book", Stock: 10})
+	require.NoError(t, err)
+
+	t.Run("Commit", func(t *testing.T) {
+		err := uow.Do(context.Background(), func(ctx context.Context) error {
+			if _, err := orders.Create(ctx, Order{Item: "book", Total: 2}); err != nil {
+				return err
+			}
+			_, err := inventory.Update(ctx, itemID, Inventory{Item: "book", Stock: 8})
+			return err
+		})
+		require.NoError(t, err)
+
+		count, err := orders.Count(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		item, err := inventory.FindByID(context.Background(), itemID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), item.Stock)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		errFailure := errors.New("ledger is unavailable")
+		err := uow.Do(context.Background(), func(ctx context.Context) error {
+			if _, err := orders.Create(ctx, Order{Item: "book", Total: 3}); err != nil {
+				return err
+			}
+			if _, err := inventory.Update(ctx, itemID, Inventory{Item: "book", Stock: 5}); err != nil {
+				return err
+			}
+			return errFailure
+		})
+		require.ErrorIs(t, err, mongorepository.ErrFailedToExecuteTransaction)
+		require.ErrorIs(t, err, errFailure)
+
+		count, err := orders.Count(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		item, err := inventory.FindByID(context.Background(), itemID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), item.Stock)
+	})
+}
+
+func TestWithSession(t *testing.T) {
+	type Account struct {
+		ID      primitive.ObjectID `bson:"_id,omitempty"`
+		Owner   string             `bson:"owner"`
+		Balance int64              `bson:"balance"`
+	}
+
+	db := setupMongoDB(t)
+	requireReplicaSet(t, db)
+
+	// Reads may be served by a secondary, lagging behind the primary
+	secondaryDB := db.Client().Database(db.Name(), options.Database().SetReadPreference(readpref.SecondaryPreferred()))
+	accounts := mongorepository.NewMongoRepository[Account](secondaryDB, "accounts")
+	uow := mongorepository.NewUnitOfWork(db.Client())
+
+	err := uow.WithSession(context.Background(), func(ctx context.Context) error {
+		id, err := accounts.Create(ctx, Account{Owner: "John", Balance: 100})
+		if err != nil {
+			return err
+		}
+		// The read sees the write made in the same session
+		account, err := accounts.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, int64(100), account.Balance)
+
+		if _, err := accounts.UpdateMany(ctx, map[string]interface{}{"balance": account.Balance + 50}, mongorepository.Eq("owner", "John")); err != nil {
+			return err
+		}
+		account, err = accounts.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, int64(150), account.Balance)
+		return nil
+	}, mongorepository.WithMajorityConcerns())
+	require.NoError(t, err)
+
+	t.Run("Error", func(t *testing.T) {
+		errFailure := errors.New("failure")
+		err := uow.WithSession(context.Background(), func(ctx context.Context) error {
+			return errFailure
+		})
+		require.ErrorIs(t, err, errFailure)
+	})
+}
@@ -0,0 +1,80 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// UpdateFunc is a function type that takes a BSON update document and adds an update operator to it.
+type UpdateFunc func(bson.D) bson.D
+
+// AddToSet creates an update adding the value to the array field unless it's already present,
+// so the array never gets duplicates. A missing field is created as an array with the value.
+func AddToSet(field string, value interface{}) UpdateFunc {
+	return func(update bson.D) bson.D {
+		return appendUpdate(update, "$addToSet", field, value)
+	}
+}
+
+// Pull creates an update removing all occurrences of the value from the array field.
+// The value can also be a condition, e.g. bson.M{"$in": []string{"admin", "owner"}}.
+func Pull(field string, value interface{}) UpdateFunc {
+	return func(update bson.D) bson.D {
+		return appendUpdate(update, "$pull", field, value)
+	}
+}
+
+// CurrentDate creates an update setting the field to the current date of the server, e.g. for updated_at.
+// Unlike a timestamp generated by the client, e.g. with time.Now(), it doesn't depend on the clock of the
+// application instance running the update, so the timestamps written by several instances with skewed clocks
+// are still ordered consistently. The time is only known once the update is applied,
+// so read the document back to get it.
+func CurrentDate(field string) UpdateFunc {
+	return func(update bson.D) bson.D {
+		return appendUpdate(update, "$currentDate", field, true)
+	}
+}
+
+// appendUpdate adds the field update to the update operator document, creating the operator if needed,
+// so that several updates with the same operator, e.g. two AddToSet, are merged together.
+func appendUpdate(update bson.D, operator, field string, value interface{}) bson.D {
+	for i, e := range update {
+		if e.Key == operator {
+			fields, _ := e.Value.(bson.D)
+			update[i].Value = append(fields, bson.E{Key: field, Value: value})
+			return update
+		}
+	}
+	return append(update, bson.E{Key: operator, Value: bson.D{{Key: field, Value: value}}})
+}
+
+// UpdateManyOps applies the update operators, e.g. AddToSet, Pull and CurrentDate, to all documents matching the filters,
+// so that e.g. array fields can be changed without reading the documents first.
+// It returns the number of documents modified and an error if any.
+func (r *mongoRepository[T]) UpdateManyOps(ctx context.Context, ops []UpdateFunc, filters ...FilterFunc) (_ int64, err error) {
+	ctx, op := r.startOperation(ctx, "UpdateManyOps")
+	defer func() { op.end(err) }()
+
+	if len(ops) == 0 {
+		return 0, errors.Join(ErrFailedToUpdateMany, ErrNoUpdateOps)
+	}
+
+	filter := bson.D{}
+	for _, f := range filters {
+		filter = f(filter)
+	}
+
+	update := bson.D{}
+	for _, u := range ops {
+		update = u(update)
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, errors.Join(ErrFailedToUpdateMany, err)
+	}
+	op.setDocumentCount(result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
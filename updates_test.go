@@ -0,0 +1,103 @@
+package mongorepository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mongorepository "github.com/dmitrymomot/mongo-repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUpdateManyOps(t *testing.T) {
+	type User struct {
+		ID    primitive.ObjectID `bson:"_id,omitempty"`
+		Name  string             `bson:"name"`
+		Roles []string           `bson:"roles"`
+		Tags  []string           `bson:"tags,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	id, err := repo.Create(context.Background(), User{Name: "John", Roles: []string{"user", "editor"}})
+	require.NoError(t, err)
+	byName := mongorepository.Eq("name", "John")
+
+	t.Run("AddToSetExisting", func(t *testing.T) {
+		modified, err := repo.UpdateManyOps(context.Background(), []mongorepository.UpdateFunc{
+			mongorepository.AddToSet("roles", "editor"),
+		}, byName)
+		require.NoError(t, err)
+		assert.Zero(t, modified)
+
+		user, err := repo.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user", "editor"}, user.Roles)
+	})
+
+	t.Run("AddToSetNew", func(t *testing.T) {
+		// Several fields at once
+		modified, err := repo.UpdateManyOps(context.Background(), []mongorepository.UpdateFunc{
+			mongorepository.AddToSet("roles", "admin"),
+			mongorepository.AddToSet("tags", "vip"),
+		}, byName)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), modified)
+
+		user, err := repo.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user", "editor", "admin"}, user.Roles)
+		assert.Equal(t, []string{"vip"}, user.Tags)
+	})
+
+	t.Run("Pull", func(t *testing.T) {
+		modified, err := repo.UpdateManyOps(context.Background(), []mongorepository.UpdateFunc{
+			mongorepository.Pull("roles", "editor"),
+		}, byName)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), modified)
+
+		user, err := repo.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user", "admin"}, user.Roles)
+	})
+
+	t.Run("NoOps", func(t *testing.T) {
+		_, err := repo.UpdateManyOps(context.Background(), nil, byName)
+		require.ErrorIs(t, err, mongorepository.ErrNoUpdateOps)
+	})
+}
+
+func TestCurrentDate(t *testing.T) {
+	type User struct {
+		ID        primitive.ObjectID `bson:"_id,omitempty"`
+		Name      string             `bson:"name"`
+		Roles     []string           `bson:"roles"`
+		UpdatedAt time.Time          `bson:"updated_at,omitempty"`
+	}
+
+	db := setupMongoDB(t)
+	repo := mongorepository.NewMongoRepository[User](db, "users")
+
+	id, err := repo.Create(context.Background(), User{Name: "John", Roles: []string{"user"}})
+	require.NoError(t, err)
+	user, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, user.UpdatedAt.IsZero())
+
+	modified, err := repo.UpdateManyOps(context.Background(), []mongorepository.UpdateFunc{
+		mongorepository.AddToSet("roles", "admin"),
+		mongorepository.CurrentDate("updated_at"),
+	}, mongorepository.Eq("name", "John"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+
+	user, err = repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user", "admin"}, user.Roles)
+	// Set by the server, so only roughly close to the client time
+	assert.WithinDuration(t, time.Now(), user.UpdatedAt, time.Minute)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -17,7 +18,7 @@ func getMongoDBURI() string {
 	return uri
 }
 
-func setupMongoDB(t *testing.T) *mongo.Database {
+func setupMongoDB(t testing.TB) *mongo.Database {
 	// Connect to MongoDB
 	uri := getMongoDBURI()
 	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(uri))
@@ -35,3 +36,15 @@ func setupMongoDB(t *testing.T) *mongo.Database {
 
 	return db
 }
+
+// requireReplicaSet skips the test if MongoDB is not deployed as a replica set,
+// which is required for transactions and change streams.
+func requireReplicaSet(t *testing.T, db *mongo.Database) {
+	var hello bson.M
+	if err := db.RunCommand(context.Background(), bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		t.Fatalf("Failed to run hello command: %v", err)
+	}
+	if _, ok := hello["setName"]; !ok {
+		t.Skip("MongoDB is not deployed as a replica set")
+	}
+}
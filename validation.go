@@ -0,0 +1,85 @@
+package mongorepository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Validator is implemented by models that can validate themselves.
+// If the model type implements it, the repository calls Validate before writing the model
+// and rejects invalid models without a database round trip.
+type Validator interface {
+	Validate() error
+}
+
+// validate calls the Validate method of the model if it implements the Validator interface,
+// either with a value or a pointer receiver. It's a no-op for other types.
+func validate[T any](model *T) error {
+	var v Validator
+	if mv, ok := any(*model).(Validator); ok {
+		v = mv
+	} else if pv, ok := any(model).(Validator); ok {
+		v = pv
+	} else {
+		return nil
+	}
+	if err := v.Validate(); err != nil {
+		return errors.Join(ErrValidation, err)
+	}
+	return nil
+}
+
+// ValidateDocument checks the model against the collection validator, e.g. a $jsonSchema,
+// without writing it, e.g. to validate a form before submitting it.
+// The model is validated with its Validate method first, if it implements the Validator interface.
+// Then the candidate document is matched against the validator read from the collection options
+// in an aggregation on a literal document, so it requires MongoDB 5.1 or later.
+// A collection without a validator accepts any document.
+// If the document is invalid, it returns an error with the ErrValidation and ErrSchemaMismatch error codes.
+func (r *mongoRepository[T]) ValidateDocument(ctx context.Context, model T) (err error) {
+	ctx, op := r.startOperation(ctx, "ValidateDocument")
+	defer func() { op.end(err) }()
+
+	if err := validate(&model); err != nil {
+		return err
+	}
+
+	specs, err := r.collection.Database().ListCollectionSpecifications(ctx, bson.D{{Key: "name", Value: r.collection.Name()}})
+	if err != nil {
+		return errors.Join(ErrValidation, err)
+	}
+	if len(specs) == 0 || specs[0].Options == nil {
+		return nil
+	}
+	validator, ok := specs[0].Options.Lookup("validator").DocumentOK()
+	if !ok || len(validator) == 0 {
+		return nil
+	}
+
+	doc, err := toBsonD(model)
+	if err != nil {
+		return errors.Join(ErrValidation, err)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$documents", Value: bson.A{doc}}},
+		{{Key: "$match", Value: validator}},
+		{{Key: "$count", Value: "matched"}},
+	}
+	cursor, err := r.collection.Database().Aggregate(ctx, pipeline)
+	if err != nil {
+		return errors.Join(ErrValidation, err)
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return errors.Join(ErrValidation, err)
+		}
+		return errors.Join(ErrValidation, ErrSchemaMismatch)
+	}
+	return nil
+}